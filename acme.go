@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+const acmeChallengePrefix string = "_acme-challenge."
+
+func acmeUsage() {
+	fmt.Printf("\nUsage:\n")
+	fmt.Printf("\tacme present|cleanup [options] [fqdn value]\n")
+	fmt.Printf("\npresent\n")
+	fmt.Printf("\tAdd DNS-01 challenge TXT record. Existing TXT values of the name are kept\n")
+	fmt.Printf("\ncleanup\n")
+	fmt.Printf("\tRemove DNS-01 challenge TXT record value. Other TXT values of the name are kept\n")
+	fmt.Printf("\nWhen --fqdn and --value are not passed, fqdn and value are read from positional arguments (lego exec provider)\n")
+	fmt.Printf("or from CERTBOT_DOMAIN and CERTBOT_VALIDATION environment variables (certbot manual hooks).\n")
+	fmt.Printf("Key and secret default to those of a configured record of the domain, or GD_KEY and GD_SECRET environment variables.\n")
+	fmt.Printf("\n\nExamples\n")
+	fmt.Printf("\tgodaddyddns acme present --fqdn='_acme-challenge.example.com' --value='token' --wait\n")
+	fmt.Printf("\tgodaddyddns acme cleanup --fqdn='_acme-challenge.example.com' --value='token'\n")
+	fmt.Printf("\tcertbot certonly --manual --preferred-challenges=dns --manual-auth-hook='godaddyddns acme present --wait' --manual-cleanup-hook='godaddyddns acme cleanup' -d '*.example.com'\n")
+}
+
+func acmeCmd(args []string, settings Settings) {
+	if len(args) < 1 || (args[0] != "present" && args[0] != "cleanup") {
+		acmeUsage()
+		os.Exit(ExitUsage)
+	}
+	action := args[0]
+
+	cmd := flag.NewFlagSet("acme "+action, flag.ExitOnError)
+	fqdn := cmd.String("fqdn", "", "Challenge record name e.g. _acme-challenge.example.com")
+	value := cmd.String("value", "", "Challenge token")
+	domain := cmd.String("domain", "", "Domain name e.g. example.com. Detected from fqdn if not passed")
+	wait := cmd.Bool("wait", false, "Wait until authoritative name servers serve the record. Only for present")
+	waitTimeout := cmd.Duration("wait-timeout", time.Duration(settings.Daemon.PropagationTimeout), "Maximum time to wait for propagation")
+	key := cmd.String("key", "", "Key value generated from godaddy developer console")
+	secret := cmd.String("secret", "", "Secret value generated from godaddy developer console")
+	cmd.Parse(args[1:])
+
+	// lego exec provider: godaddyddns acme present <fqdn> <value>
+	if *fqdn == "" && cmd.NArg() >= 2 {
+		*fqdn = cmd.Arg(0)
+		*value = cmd.Arg(1)
+	}
+
+	// certbot manual hooks
+	if *fqdn == "" && os.Getenv("CERTBOT_DOMAIN") != "" {
+		*fqdn = acmeChallengePrefix + strings.TrimPrefix(os.Getenv("CERTBOT_DOMAIN"), "*.")
+		*value = os.Getenv("CERTBOT_VALIDATION")
+	}
+
+	if *fqdn == "" || *value == "" {
+		fmt.Println("ERROR fqdn and value are mandatory")
+		fmt.Printf("\nUsage of acme %s:\n", action)
+		cmd.PrintDefaults()
+		os.Exit(ExitUsage)
+	}
+
+	if *key == "" && *secret == "" {
+		*key = os.Getenv("GD_KEY")
+		*secret = os.Getenv("GD_SECRET")
+	}
+
+	settings = settings.overlay(&Settings{Daemon: &DaemonSettings{PropagationTimeout: Duration(*waitTimeout)}})
+	err := acmeChallenge(settings, action, strings.TrimSuffix(*fqdn, "."), *value, *domain, *key, *secret, *wait)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", strings.TrimSuffix(*fqdn, "."), "Failed to "+action+" ACME challenge. "+err.Error())
+		os.Exit(exitCode(err))
+	}
+}
+
+// findZone returns the GoDaddy domain which fqdn belongs to, and credentials for it.
+// Configured records are checked first, then the domains of the GoDaddy account.
+func findZone(settings Settings, fqdn, key, secret string) (string, string, string, error) {
+	labels := strings.Split(strings.ToLower(fqdn), ".")
+
+	config, err := loadConfiguration()
+	if err == nil {
+		for i := 0; i < len(labels)-1; i++ {
+			candidate := strings.Join(labels[i:], ".")
+			for _, record := range config.Config {
+				if strings.EqualFold(record.Domain, candidate) {
+					if key == "" || secret == "" {
+						record, err = resolveCredentials(config, record)
+						if err != nil {
+							return "", "", "", err
+						}
+						key, secret = record.Key, record.Secret
+					}
+					return record.Domain, key, secret, nil
+				}
+			}
+		}
+	}
+
+	if key == "" || secret == "" {
+		return "", "", "", &ConfigError{Op: "Error finding credentials in", Err: ErrNoCredentials}
+	}
+
+	domains, err := newGodaddyClient(settings.HTTP, "", fqdn, key, secret).ListDomains(context.Background())
+	if err != nil {
+		return "", "", "", err
+	}
+	for i := 0; i < len(labels)-1; i++ {
+		candidate := strings.Join(labels[i:], ".")
+		for _, d := range domains {
+			if strings.EqualFold(d.Domain, candidate) {
+				return d.Domain, key, secret, nil
+			}
+		}
+	}
+
+	return "", "", "", errors.New("no domain in GoDaddy account matches " + fqdn)
+}
+
+func acmeChallenge(settings Settings, action, fqdn, value, domain, key, secret string, wait bool) error {
+	var err error
+	if domain == "" {
+		domain, key, secret, err = findZone(settings, fqdn, key, secret)
+	} else {
+		key, secret, err = credentialsForDomain(domain, key, secret)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !dns.IsSubDomain(dns.Fqdn(strings.ToLower(domain)), dns.Fqdn(strings.ToLower(fqdn))) {
+		return fmt.Errorf("%s is not in domain %s", fqdn, domain)
+	}
+	name := relativeName(fqdn, domain)
+
+	client := newGodaddyClient(settings.HTTP, name, domain, key, secret)
+	ctx := context.Background()
+
+	if action == "present" {
+		err = presentChallenge(ctx, client, domain, name, value)
+	} else {
+		err = cleanupChallenge(ctx, client, domain, name, value)
+	}
+	if err != nil {
+		return err
+	}
+
+	if action == "present" {
+		GoDaddyDDNSLogger(InformationLog, name, domain, "ACME challenge TXT record present")
+		if wait {
+			GoDaddyDDNSLogger(InformationLog, name, domain, "Waiting for authoritative name servers to serve the challenge")
+			err = waitForTXT(domain, fqdn, value, settings.Daemon)
+			if err != nil {
+				return err
+			}
+			GoDaddyDDNSLogger(InformationLog, name, domain, "ACME challenge is live on all authoritative name servers")
+		}
+	} else {
+		GoDaddyDDNSLogger(InformationLog, name, domain, "ACME challenge TXT record removed")
+	}
+
+	return nil
+}
+
+// presentChallenge adds the TXT value with PATCH, so that challenges of other names and of
+// parallel hooks for the same name (e.g. domain and its wildcard) are kept.
+func presentChallenge(ctx context.Context, client *godaddy.Client, domain, name, value string) error {
+	existing, err := client.GetRecords(ctx, domain, godaddy.TypeTXT, name)
+	if err != nil {
+		return err
+	}
+	for _, record := range existing {
+		if record.Data == value {
+			return nil
+		}
+	}
+	return client.AddRecords(ctx, domain, []godaddy.GodaddyRecordBody{{Type: godaddy.TypeTXT, Name: name, Data: value, TTL: 600}})
+}
+
+// cleanupChallenge removes the TXT value and keeps other values of the name. GoDaddy has no
+// request removing a single value, so the remaining values are written back right after
+// reading them, and the removal is checked, as a parallel cleanup may write our value back.
+func cleanupChallenge(ctx context.Context, client *godaddy.Client, domain, name, value string) error {
+	for attempt := 0; ; attempt++ {
+		existing, err := client.GetRecords(ctx, domain, godaddy.TypeTXT, name)
+		if err != nil {
+			return err
+		}
+
+		var records []godaddy.GodaddyRecordBody
+		found := false
+		for _, record := range existing {
+			if record.Data == value {
+				found = true
+				continue
+			}
+			records = append(records, record)
+		}
+		if !found {
+			return nil
+		}
+		if attempt == 3 {
+			return fmt.Errorf("challenge of %s keeps being written back by another client", name)
+		}
+
+		if len(records) == 0 {
+			err = client.DeleteRecords(ctx, domain, godaddy.TypeTXT, name)
+		} else {
+			err = client.ReplaceRecords(ctx, domain, godaddy.TypeTXT, name, records)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// recordHealth tracks consecutive failures of a record in daemon.
+type recordHealth struct {
+	failures    int
+	nextAttempt time.Time
+	paused      bool
+	fingerprint string
+}
+
+// recordBreaker backs off polling of failing records. Records failing with permanent errors
+// (authentication, authorization or domain not found) are paused until their configuration changes.
+// Delay after a failure doubles from poll interval up to max backoff.
+type recordBreaker struct {
+	mu           sync.Mutex
+	states       map[string]*recordHealth
+	pollInterval time.Duration
+	maxBackoff   time.Duration
+}
+
+func newRecordBreaker(pollInterval, maxBackoff time.Duration) *recordBreaker {
+	return &recordBreaker{states: make(map[string]*recordHealth), pollInterval: pollInterval, maxBackoff: maxBackoff}
+}
+
+func recordId(record DNSRecord) string {
+	return record.Name + "." + record.Domain
+}
+
+// Record state is reset whenever credentials, ttl or strategy of the record are updated.
+func recordFingerprint(record DNSRecord) string {
+	return record.Key + ":" + record.Secret + ":" + fmt.Sprintf("%d", record.TTL) + ":" + record.Strategy
+}
+
+func (b *recordBreaker) state(record DNSRecord) *recordHealth {
+	id := recordId(record)
+	fingerprint := recordFingerprint(record)
+
+	state, ok := b.states[id]
+	if !ok || state.fingerprint != fingerprint {
+		if ok && state.paused {
+			GoDaddyDDNSLogger(InformationLog, record.Name, record.Domain, "Record configuration changed. Resuming paused record")
+		}
+		state = &recordHealth{fingerprint: fingerprint}
+		b.states[id] = state
+	}
+	return state
+}
+
+// allow reports if the record should be polled now.
+func (b *recordBreaker) allow(record DNSRecord, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(record)
+	if state.paused {
+		return false
+	}
+	return !now.Before(state.nextAttempt)
+}
+
+func (b *recordBreaker) success(record DNSRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(record)
+	if state.failures > 0 {
+		GoDaddyDDNSLogger(InformationLog, record.Name, record.Domain, "Record recovered after "+fmt.Sprintf("%d", state.failures)+" consecutive failure(s)")
+	}
+	state.failures = 0
+	state.nextAttempt = time.Time{}
+}
+
+// failure records a failed poll and returns true if the record got paused.
+func (b *recordBreaker) failure(record DNSRecord, err error, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state(record)
+	state.failures++
+
+	if isPermanentError(err) {
+		state.paused = true
+		return true
+	}
+
+	delay := b.maxBackoff
+	if state.failures <= 20 {
+		delay = b.pollInterval << uint(state.failures-1)
+	}
+	if delay <= 0 || delay > b.maxBackoff {
+		delay = b.maxBackoff
+	}
+	state.nextAttempt = now.Add(delay)
+	GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, fmt.Sprintf("%d", state.failures)+" consecutive failure(s). Next attempt in "+delay.String())
+	return false
+}
+
+// isPermanentError reports if error is not expected to go away without user action.
+func isPermanentError(err error) bool {
+	var authErr *godaddy.AuthError
+	var notFoundErr *godaddy.NotFoundError
+	return errors.As(err, &authErr) || errors.As(err, &notFoundErr)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// newGodaddyClient returns GoDaddy API client for the record. Retries are logged against the record.
+func newGodaddyClient(settings *HTTPSettings, name, domain, key, secret string) *godaddy.Client {
+	client := godaddy.NewClient(key, secret)
+	client.HTTPClient = &http.Client{Timeout: time.Duration(settings.Timeout)}
+	client.BaseURL = strings.TrimSuffix(settings.APIURL, "/") + "/" + settings.APIVersion
+	client.UserAgent = "godaddy-ddns/" + version
+	client.MaxRetries = *settings.MaxRetries
+	client.RetryBaseDelay = time.Duration(settings.RetryBaseDelay)
+	client.RetryMaxDelay = time.Duration(settings.RetryMaxDelay)
+	client.Limiter = sharedRateLimiter(settings.RateLimit)
+	client.OnRetry = func(method, path string, delay time.Duration, reason string) {
+		GoDaddyDDNSLogger(WarningLog, name, domain, "GoDaddy API request failed ("+reason+"). Retrying in "+delay.Round(time.Millisecond).String())
+	}
+	return client
+}
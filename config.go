@@ -0,0 +1,83 @@
+package main
+
+// loadConfiguration reads the configuration file. Empty file is an empty configuration.
+func loadConfiguration() (Configuration, error) {
+	var config Configuration
+
+	configFileContent, err := readConfigFile()
+	if err != nil {
+		return config, &ConfigError{Op: "Error reading", Err: err}
+	}
+
+	if len(configFileContent) == 0 {
+		return config, nil
+	}
+
+	config, err = decodeConfiguration(configFileContent)
+	if err != nil {
+		return config, &ConfigError{Op: "Error unmarshalling", Err: err}
+	}
+
+	return config, nil
+}
+
+// encodeConfiguration returns content of configuration file. If encryption is enabled,
+// plain text secrets are encrypted.
+func encodeConfiguration(config Configuration) ([]byte, error) {
+	config.Version = currentConfigVersion
+
+	if config.Encryption != nil {
+		copySecrets(&config)
+		err := transformSecrets(&config, func(value string) (string, error) {
+			return encryptSecret(config.Encryption, value)
+		})
+		if err != nil {
+			return nil, &ConfigError{Op: "Error encrypting", Err: err}
+		}
+	}
+
+	configFileContent, err := marshalConfiguration(config)
+	if err != nil {
+		return nil, &ConfigError{Op: "Error marshalling", Err: err}
+	}
+	return configFileContent, nil
+}
+
+// saveConfiguration writes the configuration file.
+func saveConfiguration(config Configuration, settings *ConfigFileSettings) error {
+	configFileContent, err := encodeConfiguration(config)
+	if err != nil {
+		return err
+	}
+
+	err = writeConfigFile(configFileContent, settings)
+	if err != nil {
+		return &ConfigError{Op: "Error writing", Err: err}
+	}
+	return nil
+}
+
+// credentialsForDomain returns key and secret passed as flags, or else those of
+// a configured record of the domain.
+func credentialsForDomain(domain, key, secret string) (string, string, error) {
+	if key != "" && secret != "" {
+		return resolveKeyAndSecret(nil, key, secret)
+	}
+
+	config, err := loadConfiguration()
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, record := range config.Config {
+		if record.Domain == domain {
+			record, err = resolveCredentials(config, record)
+			if err != nil {
+				return "", "", err
+			}
+			return record.Key, record.Secret, nil
+		}
+	}
+
+	return "", "", &ConfigError{Op: "Error finding credentials in", Err: ErrNoCredentials}
+}
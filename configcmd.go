@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func configUsage() {
+	fmt.Printf("\nUsage:\n")
+	fmt.Printf("\tconfig encrypt|decrypt|validate|schema|migrate [options]\n")
+	fmt.Printf("\nencrypt\n")
+	fmt.Printf("\tEncrypt keys and secrets stored in configuration\n")
+	fmt.Printf("\ndecrypt\n")
+	fmt.Printf("\tStore keys and secrets in configuration as plain text again\n")
+	fmt.Printf("\nvalidate\n")
+	fmt.Printf("\tCheck configuration file and print problems with their line\n")
+	fmt.Printf("\nschema\n")
+	fmt.Printf("\tPrint JSON Schema of configuration file\n")
+	fmt.Printf("\nmigrate\n")
+	fmt.Printf("\tUpgrade configuration file of older release to version %d. Previous file is kept as <file>.v<version>.bak\n", currentConfigVersion)
+	fmt.Printf("\tRecords of godaddy-ddns.properties of vZ releases are added with --from\n")
+	fmt.Printf("\nConfiguration file can be json, yaml (.yaml, .yml) or toml (.toml), chosen by its extension.\n")
+	fmt.Printf("\nPassphrase is read from GODADDYDDNS_PASSPHRASE, file in GODADDYDDNS_PASSPHRASE_FILE or terminal.\n")
+	fmt.Printf("age identity is read from file in GODADDYDDNS_IDENTITY_FILE.\n")
+	fmt.Printf("\n\nExamples\n")
+	fmt.Printf("\tgodaddyddns config encrypt\n")
+	fmt.Printf("\tgodaddyddns config encrypt --method=age --recipient='age1...'\n")
+	fmt.Printf("\tgodaddyddns config decrypt\n")
+	fmt.Printf("\tgodaddyddns --config=config.yaml config validate\n")
+	fmt.Printf("\tgodaddyddns config migrate --from=/path/to/godaddy-ddns.properties\n")
+}
+
+func configCmd(args []string, settings Settings) {
+	if len(args) < 1 {
+		configUsage()
+		os.Exit(ExitUsage)
+	}
+
+	action := args[0]
+	var err error
+
+	switch action {
+	case "encrypt":
+		cmd := flag.NewFlagSet("config encrypt", flag.ExitOnError)
+		method := cmd.String("method", EncryptionScrypt, "Encryption method. 'scrypt' (passphrase) or 'age' (identity file)")
+		recipient := cmd.String("recipient", "", "age recipient to encrypt to. Defaults to recipient of identity in GODADDYDDNS_IDENTITY_FILE")
+		cmd.Parse(args[1:])
+		if *method != EncryptionScrypt && *method != EncryptionAge {
+			fmt.Println("ERROR method must be one of scrypt, age")
+			os.Exit(ExitUsage)
+		}
+		err = encryptConfiguration(settings, *method, *recipient)
+	case "decrypt":
+		cmd := flag.NewFlagSet("config decrypt", flag.ExitOnError)
+		cmd.Parse(args[1:])
+		err = decryptConfiguration(settings)
+	case "validate":
+		cmd := flag.NewFlagSet("config validate", flag.ExitOnError)
+		cmd.Parse(args[1:])
+		err = validateConfigFile()
+	case "schema":
+		os.Stdout.Write(configSchema)
+	case "migrate":
+		cmd := flag.NewFlagSet("config migrate", flag.ExitOnError)
+		from := cmd.String("from", "", "godaddy-ddns.properties file of vZ releases to add records from")
+		dryRun := cmd.Bool("dry-run", false, "Print migrated configuration without writing it")
+		cmd.Parse(args[1:])
+		err = migrateConfigFile(settings, *from, *dryRun)
+	default:
+		configUsage()
+		os.Exit(ExitUsage)
+	}
+
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to "+action+" configuration. "+err.Error())
+		os.Exit(exitCode(err))
+	}
+}
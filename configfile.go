@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ConfigJSON string = "json"
+	ConfigYAML string = "yaml"
+	ConfigTOML string = "toml"
+)
+
+// configFormat returns format of configuration file from its extension. Default is json.
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ConfigYAML
+	case ".toml":
+		return ConfigTOML
+	}
+	return ConfigJSON
+}
+
+// configFileFormat returns format of the configuration file in use. Without a known path
+// nothing has been read from the file, and json is returned.
+func configFileFormat() string {
+	path, _ := configFilePath()
+	return configFormat(path)
+}
+
+// normalizeValue converts yaml and toml values to values json can encode. Whole numbers
+// are kept as integers so that they are not written as 600.0, and null values are dropped.
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if item == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = normalizeValue(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeValue(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeValue(item)
+		}
+		return v
+	case []map[string]interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = normalizeValue(item)
+		}
+		return items
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	}
+	return value
+}
+
+// parseConfigMap parses content in format of configuration file without decoding it into
+// Configuration. Empty content gives nil map.
+func parseConfigMap(content []byte) (map[string]interface{}, error) {
+	var generic map[string]interface{}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, nil
+	}
+
+	var err error
+	switch configFileFormat() {
+	case ConfigYAML:
+		err = yaml.Unmarshal(content, &generic)
+	case ConfigTOML:
+		err = toml.Unmarshal(content, &generic)
+	default:
+		err = json.Unmarshal(content, &generic)
+	}
+	if err != nil || generic == nil {
+		return nil, err
+	}
+	return normalizeValue(generic).(map[string]interface{}), nil
+}
+
+// decodeConfiguration parses configuration in format of configuration file and upgrades
+// older versions. Values are converted to json first, so that json field names apply to
+// all formats.
+func decodeConfiguration(content []byte) (Configuration, error) {
+	var config Configuration
+
+	generic, err := parseConfigMap(content)
+	if err != nil || generic == nil {
+		return config, err
+	}
+
+	_, err = migrateConfigMap(generic)
+	if err != nil {
+		return config, err
+	}
+
+	content, err = json.Marshal(generic)
+	if err != nil {
+		return config, err
+	}
+
+	err = json.Unmarshal(content, &config)
+	return config, err
+}
+
+// marshalConfiguration returns configuration in format of configuration file.
+func marshalConfiguration(config Configuration) ([]byte, error) {
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	format := configFileFormat()
+	if format == ConfigJSON {
+		return content, nil
+	}
+
+	var out bytes.Buffer
+	if format == ConfigYAML {
+		// json is read as yaml to keep fields in order of the structs
+		var node yaml.Node
+		err = yaml.Unmarshal(content, &node)
+		if err != nil {
+			return nil, err
+		}
+		blockStyle(&node)
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(2)
+		err = encoder.Encode(&node)
+		if err == nil {
+			err = encoder.Close()
+		}
+		return out.Bytes(), err
+	}
+
+	var generic map[string]interface{}
+	err = json.Unmarshal(content, &generic)
+	if err != nil {
+		return nil, err
+	}
+	err = toml.NewEncoder(&out).Encode(normalizeValue(generic))
+	return out.Bytes(), err
+}
+
+// blockStyle clears json styles of node, so that it is written as plain yaml, and drops null fields.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.MappingNode {
+		content := node.Content[:0]
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i+1].ShortTag() == "!!null" {
+				continue
+			}
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+		node.Content = content
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,712 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configSchema is JSON Schema of configuration file. It applies to yaml and toml files as well.
+//
+//go:embed schema/config.schema.json
+var configSchema []byte
+
+// configProblem is a problem found in configuration file. Line is 0 if position is not known.
+type configProblem struct {
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (p configProblem) String() string {
+	// Problems are found only in a file which has been read
+	position, _ := configFilePath()
+	if p.Line > 0 {
+		position = fmt.Sprintf("%s:%d", position, p.Line)
+	}
+	if p.Column > 0 {
+		position = fmt.Sprintf("%s:%d", position, p.Column)
+	}
+	if p.Path == "" {
+		return position + ": " + p.Message
+	}
+	return position + ": " + p.Path + ": " + p.Message
+}
+
+// configValidator checks a configuration file. Nodes are indexed by path of the field,
+// e.g. config[0].ttl, to report problems found in decoded configuration at their line.
+// Values of wrong type are left out when configuration is decoded for value checks.
+type configValidator struct {
+	nodes    map[string]*yaml.Node
+	invalid  map[*yaml.Node]string
+	problems []configProblem
+}
+
+func (v *configValidator) reportNode(node *yaml.Node, path, format string, args ...interface{}) {
+	v.problems = append(v.problems, configProblem{Line: node.Line, Column: node.Column, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// reportType adds a problem with type of the value at node.
+func (v *configValidator) reportType(node *yaml.Node, path, message string) {
+	v.invalid[node] = path
+	v.reportNode(node, path, "%s", message)
+}
+
+// report adds a problem at the field. Position of closest parent is used if the field is not set.
+func (v *configValidator) report(path, format string, args ...interface{}) {
+	for parent := path; parent != ""; {
+		if node, ok := v.nodes[parent]; ok {
+			v.reportNode(node, path, format, args...)
+			return
+		}
+		if i := strings.LastIndexAny(parent, ".["); i > 0 {
+			parent = parent[:i]
+		} else {
+			parent = ""
+		}
+	}
+	v.problems = append(v.problems, configProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// parse reads content into yaml nodes, which keep line and column of every value. json is
+// read by yaml parser as well. toml values are converted to nodes without position.
+func (v *configValidator) parse(format string, content []byte) *yaml.Node {
+	var node yaml.Node
+
+	switch format {
+	case ConfigJSON:
+		var generic interface{}
+		err := json.Unmarshal(content, &generic)
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, column := offsetPosition(content, syntaxErr.Offset)
+			v.problems = append(v.problems, configProblem{Line: line, Column: column, Message: syntaxErr.Error()})
+			return nil
+		}
+		if err != nil {
+			v.problems = append(v.problems, configProblem{Message: err.Error()})
+			return nil
+		}
+		if yaml.Unmarshal(content, &node) != nil {
+			// Valid json which yaml cannot read, e.g. tabs. Positions are lost.
+			node = yaml.Node{}
+			if err := node.Encode(generic); err != nil {
+				v.problems = append(v.problems, configProblem{Message: err.Error()})
+				return nil
+			}
+		}
+	case ConfigYAML:
+		if err := yaml.Unmarshal(content, &node); err != nil {
+			v.problems = append(v.problems, configProblem{Message: err.Error()})
+			return nil
+		}
+	case ConfigTOML:
+		var generic map[string]interface{}
+		meta, err := toml.Decode(string(content), &generic)
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			v.problems = append(v.problems, configProblem{Line: parseErr.Position.Line, Message: strings.TrimPrefix(parseErr.Error(), "toml: ")})
+			return nil
+		}
+		if err != nil {
+			v.problems = append(v.problems, configProblem{Message: err.Error()})
+			return nil
+		}
+		if err := node.Encode(normalizeValue(generic)); err != nil {
+			v.problems = append(v.problems, configProblem{Message: err.Error()})
+			return nil
+		}
+		setLines(&node, "", tomlLines(content, meta))
+	}
+
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return node.Content[0]
+	}
+	return &node
+}
+
+// tomlLines returns line of each key in toml content by path of the field, e.g.
+// config[1].ttl. toml decoder keeps no position of values, so keys listed by MetaData in
+// order of the file are looked up in the lines following the previous key. Keys of inline
+// tables get the line of the table.
+func tomlLines(content []byte, meta toml.MetaData) map[string]int {
+	positions := make(map[string]int)
+	lines := strings.Split(string(content), "\n")
+	tables := make(map[string]int) // Count of [[table]] headers by key
+	var table []string             // Key of current table
+	cursor := 0
+
+	for _, key := range meta.Keys() {
+		end := cursor
+		for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), "[") {
+			end++
+		}
+
+		// Table header
+		if t := meta.Type(key...); (t == "Hash" || t == "ArrayHash") && end < len(lines) && equalKeys(tomlHeader(lines[end]), key) {
+			if strings.HasPrefix(strings.TrimSpace(lines[end]), "[[") {
+				tables[key.String()]++
+			}
+			table = key
+			positions[tomlPath(key, tables)] = end + 1
+			cursor = end + 1
+			continue
+		}
+
+		// key = value, or the key of inline table or dotted key holding it
+		if len(key) < len(table) {
+			continue
+		}
+		relative := key[len(table):]
+		for i := cursor; i < end; i++ {
+			defined := tomlKey(lines[i])
+			if len(defined) == 0 || len(defined) > len(relative) || !equalKeys(defined, relative[:len(defined)]) {
+				continue
+			}
+			positions[tomlPath(key, tables)] = i + 1
+			if len(defined) == len(relative) {
+				cursor = i + 1
+			} else {
+				cursor = i
+			}
+			break
+		}
+	}
+	return positions
+}
+
+// tomlPath returns path of the field of key, with index of the current entry of each
+// array of tables.
+func tomlPath(key toml.Key, tables map[string]int) string {
+	path := ""
+	for i, name := range key {
+		path = joinPath(path, name)
+		if count := tables[key[:i+1].String()]; count > 0 {
+			path = fmt.Sprintf("%s[%d]", path, count-1)
+		}
+	}
+	return path
+}
+
+// tomlHeader returns key of [table] or [[table]] header line.
+func tomlHeader(line string) []string {
+	line = strings.TrimSpace(line)
+	if i := strings.LastIndex(line, "]"); i > 0 && strings.HasPrefix(line, "[") {
+		return splitTomlKey(strings.Trim(line[:i+1], "[]"))
+	}
+	return nil
+}
+
+// tomlKey returns key of key = value line.
+func tomlKey(line string) []string {
+	if i := strings.Index(line, "="); i > 0 {
+		return splitTomlKey(line[:i])
+	}
+	return nil
+}
+
+// splitTomlKey splits dotted key into its names. Quoted names may contain dots.
+func splitTomlKey(key string) []string {
+	var names []string
+	var name strings.Builder
+	quote := rune(0)
+	for _, c := range key {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			name.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			names = append(names, strings.TrimSpace(name.String()))
+			name.Reset()
+		case c != ' ' && c != '\t':
+			name.WriteRune(c)
+		}
+	}
+	return append(names, name.String())
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// setLines sets line of the nodes of fields found in lines.
+// Fields without line get the line of their parent.
+func setLines(node *yaml.Node, path string, lines map[string]int) {
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			setLines(child, path, lines)
+		}
+		return
+	}
+	if line, ok := lines[path]; ok {
+		node.Line = line
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			value.Line = node.Line
+			setLines(value, joinPath(path, key.Value), lines)
+			key.Line = value.Line
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			item.Line = node.Line
+			setLines(item, fmt.Sprintf("%s[%d]", path, i), lines)
+		}
+	}
+}
+
+// offsetPosition returns line and column of byte offset in content.
+func offsetPosition(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := strings.Count(string(before), "\n") + 1
+	column := len(before) - strings.LastIndex(string(before), "\n")
+	return line, column
+}
+
+// jsonField returns the struct field stored under name. Names are matched case-insensitively
+// like encoding/json does.
+func jsonField(t reflect.Type, name string) (reflect.StructField, string, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := jsonName(field)
+		if tag == "-" {
+			continue
+		}
+		if strings.EqualFold(tag, name) {
+			return field, tag, true
+		}
+	}
+	return reflect.StructField{}, "", false
+}
+
+func jsonName(field reflect.StructField) string {
+	if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" {
+		return tag
+	}
+	return field.Name
+}
+
+// walk checks that node has the type t expects and reports unknown fields.
+func (v *configValidator) walk(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if path != "" {
+		v.nodes[path] = node
+	}
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		return
+	}
+
+	if t == reflect.TypeOf(Duration(0)) {
+		if _, err := time.ParseDuration(node.Value); node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" || err != nil {
+			v.reportType(node, path, "must be a duration like 90s or 5m")
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		v.walk(node, t.Elem(), path)
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.reportType(node, path, "must be an object")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, name, ok := jsonField(t, key.Value)
+			if !ok {
+				v.reportNode(key, joinPath(path, key.Value), "unknown field")
+				continue
+			}
+			v.walk(value, field.Type, joinPath(path, name))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.reportType(node, path, "must be an object")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.walk(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.reportType(node, path, "must be a list")
+			return
+		}
+		for i, item := range node.Content {
+			v.walk(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" {
+			v.reportType(node, path, "must be a string")
+		}
+	case reflect.Int:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" {
+			v.reportType(node, path, "must be an integer")
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			v.reportType(node, path, "must be true or false")
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func (v *configValidator) checkHooks(hooks *Hooks, path string) {
+	if hooks == nil {
+		return
+	}
+	for name, hook := range map[string]*Hook{"pre_update": hooks.PreUpdate, "post_update": hooks.PostUpdate, "notify": hooks.Notify} {
+		if hook == nil {
+			continue
+		}
+		if strings.TrimSpace(hook.Command) == "" {
+			v.report(joinPath(path, name)+".command", "is required")
+		}
+		if hook.Timeout < 0 {
+			v.report(joinPath(path, name)+".timeout", "must not be negative")
+		}
+	}
+}
+
+func (v *configValidator) checkURL(value, path string) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.report(path, "must be an http or https URL")
+	}
+}
+
+// checkSettings reports invalid values in settings block at path.
+func (v *configValidator) checkSettings(settings *Settings, path string) {
+	if settings == nil {
+		return
+	}
+
+	// Durations are checked for all sections
+	sections := reflect.ValueOf(settings).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		if sections.Field(i).IsNil() {
+			continue
+		}
+		section := sections.Field(i).Elem()
+		for j := 0; j < section.NumField(); j++ {
+			if d, ok := section.Field(j).Interface().(Duration); ok && d < 0 {
+				v.report(joinPath(joinPath(path, jsonName(sections.Type().Field(i))), jsonName(section.Type().Field(j))), "must not be negative")
+			}
+		}
+	}
+
+	if daemon := settings.Daemon; daemon != nil {
+		if daemon.Lookup != "" && daemon.Lookup != "api" && daemon.Lookup != "dns" {
+			v.report(path+".daemon.lookup", "must be one of api, dns")
+		}
+		if daemon.Workers < 0 {
+			v.report(path+".daemon.workers", "must not be negative")
+		}
+		if daemon.MaxRecords < 0 {
+			v.report(path+".daemon.max_records", "must not be negative")
+		}
+	}
+	if logging := settings.Logging; logging != nil {
+		switch strings.ToLower(logging.Level) {
+		case "", "info", "warn", "error":
+		default:
+			v.report(path+".logging.level", "must be one of info, warn, error")
+		}
+	}
+	if ipDetection := settings.IPDetection; ipDetection != nil {
+		for i, source := range ipDetection.Sources {
+			v.checkURL(source, fmt.Sprintf("%s.ip_detection.sources[%d]", path, i))
+		}
+	}
+	if httpSettings := settings.HTTP; httpSettings != nil {
+		if httpSettings.MaxRetries != nil && *httpSettings.MaxRetries < 0 {
+			v.report(path+".http.max_retries", "must not be negative")
+		}
+		if httpSettings.RateLimit < 0 {
+			v.report(path+".http.rate_limit", "must not be negative")
+		}
+		if httpSettings.APIURL != "" {
+			v.checkURL(httpSettings.APIURL, path+".http.api_url")
+		}
+	}
+	if configFile := settings.ConfigFile; configFile != nil {
+		if configFile.Backups != nil && *configFile.Backups < 0 {
+			v.report(path+".config_file.backups", "must not be negative")
+		}
+	}
+	if notifications := settings.Notifications; notifications != nil {
+		for i, event := range notifications.Events {
+			known := false
+			for _, e := range notifyEvents {
+				known = known || e == event
+			}
+			if !known {
+				v.report(fmt.Sprintf("%s.notifications.events[%d]", path, i), "must be one of %s", strings.Join(notifyEvents, ", "))
+			}
+		}
+	}
+}
+
+// checkSecret reports encrypted values in configuration without encryption settings.
+func (v *configValidator) checkSecret(config Configuration, value, path string) {
+	if isEncrypted(value) && config.Encryption == nil {
+		v.report(path, "is encrypted but configuration has no encryption settings")
+	}
+}
+
+// check reports problems in values of decoded configuration.
+func (v *configValidator) check(config Configuration) {
+	settings := defaultSettings().overlay(config.Settings)
+	if len(config.Config) > settings.Daemon.MaxRecords {
+		v.report("config", "maximum %v records allowed per server", settings.Daemon.MaxRecords)
+	}
+
+	seen := make(map[string]int)
+	for i, record := range config.Config {
+		path := fmt.Sprintf("config[%d]", i)
+
+		if record.Domain == "" {
+			v.report(path+".domain", "is required")
+		}
+		if record.Name == "" {
+			v.report(path+".name", "is required")
+		}
+		if record.TTL < 600 {
+			v.report(path+".ttl", "must be at least 600 seconds")
+		}
+
+		id := strings.ToLower(record.Domain + "/" + record.Name)
+		if first, ok := seen[id]; ok && record.Domain != "" && record.Name != "" {
+			v.report(path, "duplicate of config[%d], record %s.%s is already configured", first, record.Name, record.Domain)
+		} else {
+			seen[id] = i
+		}
+
+		if record.Profile != "" {
+			if _, ok := config.Credentials[record.Profile]; !ok {
+				v.report(path+".profile", "credentials profile %q is not defined", record.Profile)
+			}
+		} else {
+			if record.Key == "" {
+				v.report(path+".key", "is required unless profile is set")
+			}
+			if record.Secret == "" {
+				v.report(path+".secret", "is required unless profile is set")
+			}
+		}
+		v.checkSecret(config, record.Key, path+".key")
+		v.checkSecret(config, record.Secret, path+".secret")
+
+		if record.Strategy != "" && record.Strategy != StrategyReplace && record.Strategy != StrategyMerge {
+			v.report(path+".strategy", "must be one of replace, merge")
+		}
+		if record.Failover != nil {
+			if err := record.Failover.validate(); err != nil {
+				v.report(path+".failover", "%s", err.Error())
+			}
+			if record.Failover.FailureThreshold < 0 || record.Failover.RecoveryThreshold < 0 {
+				v.report(path+".failover", "thresholds must not be negative")
+			}
+		}
+		v.checkHooks(record.Hooks, path+".hooks")
+
+		v.checkSettings(record.Settings, path+".settings")
+		for _, global := range globalOnlySettings {
+			if _, ok := v.nodes[path+".settings."+global]; ok {
+				v.report(path+".settings."+global, "can only be set in global settings")
+			}
+		}
+	}
+
+	v.checkSettings(config.Settings, "settings")
+
+	for profile, credential := range config.Credentials {
+		path := joinPath("credentials", profile)
+		if credential.Key == "" {
+			v.report(path+".key", "is required")
+		}
+		if credential.Secret == "" {
+			v.report(path+".secret", "is required")
+		}
+		v.checkSecret(config, credential.Key, path+".key")
+		v.checkSecret(config, credential.Secret, path+".secret")
+	}
+
+	v.checkHooks(config.Hooks, "hooks")
+
+	if config.Encryption != nil {
+		switch config.Encryption.Method {
+		case EncryptionScrypt:
+			if _, err := base64.StdEncoding.DecodeString(config.Encryption.Salt); err != nil || config.Encryption.Salt == "" {
+				v.report("encryption.salt", "must be base64 encoded salt")
+			}
+		case EncryptionAge:
+			if _, err := age.ParseX25519Recipient(config.Encryption.Recipient); err != nil {
+				v.report("encryption.recipient", "must be an age recipient")
+			}
+		default:
+			v.report("encryption.method", "must be one of scrypt, age")
+		}
+	}
+}
+
+// withoutInvalid returns copy of node without values of wrong type. Invalid items of lists
+// are kept as null, so that following items keep their index.
+func (v *configValidator) withoutInvalid(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	copied := *node
+	copied.Content = nil
+	for _, child := range node.Content {
+		if _, ok := v.invalid[child]; !ok {
+			copied.Content = append(copied.Content, v.withoutInvalid(child))
+			continue
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			// Key is dropped with the value
+			copied.Content = copied.Content[:len(copied.Content)-1]
+		case yaml.SequenceNode:
+			copied.Content = append(copied.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"})
+		}
+	}
+	return &copied
+}
+
+// withoutInvalidPaths drops problems in values of wrong type, which are reported already.
+func (v *configValidator) withoutInvalidPaths(problems []configProblem) []configProblem {
+	var kept []configProblem
+	for _, problem := range problems {
+		reported := false
+		for _, path := range v.invalid {
+			reported = reported || problem.Path == path || strings.HasPrefix(problem.Path, path+".") || strings.HasPrefix(problem.Path, path+"[")
+		}
+		if !reported {
+			kept = append(kept, problem)
+		}
+	}
+	return kept
+}
+
+// validateConfiguration returns problems found in content of configuration file.
+// Secrets are not decrypted.
+func validateConfiguration(content []byte) []configProblem {
+	v := &configValidator{nodes: make(map[string]*yaml.Node), invalid: make(map[*yaml.Node]string)}
+
+	root := v.parse(configFileFormat(), content)
+	if root == nil {
+		return v.problems
+	}
+	v.walk(root, reflect.TypeOf(Configuration{}), "")
+
+	generic, err := parseConfigMap(content)
+	if err == nil {
+		var version int
+		version, err = configVersion(generic)
+		if err == nil && version < currentConfigVersion {
+			err = fmt.Errorf("configuration is version %d. Run config migrate to upgrade it to version %d", version, currentConfigVersion)
+		}
+	}
+	if err != nil {
+		// Values of older versions are checked after migration
+		v.report("version", "%s", err.Error())
+		return v.problems
+	}
+
+	// Values are checked in configuration without the values of wrong type, whose
+	// problems are reported already
+	var decoded interface{}
+	err = v.withoutInvalid(root).Decode(&decoded)
+	if err == nil {
+		content, err = json.Marshal(normalizeValue(decoded))
+	}
+	var config Configuration
+	if err == nil {
+		err = json.Unmarshal(content, &config)
+	}
+	if err != nil {
+		v.problems = append(v.problems, configProblem{Message: err.Error()})
+		return v.problems
+	}
+	typeProblems := len(v.problems)
+	v.check(config)
+	v.problems = append(v.problems[:typeProblems], v.withoutInvalidPaths(v.problems[typeProblems:])...)
+
+	// Profiles and hooks are checked in map order
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		a, b := v.problems[i], v.problems[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return a.Path < b.Path
+	})
+	return v.problems
+}
+
+// validateConfigFile prints problems found in configuration file.
+func validateConfigFile() error {
+	path, err := configFilePath()
+	if err != nil {
+		return &ConfigError{Op: "Error reading", Err: err}
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return &ConfigError{Op: "Error reading", Err: err}
+	}
+
+	problems := validateConfiguration(content)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) != 0 {
+		return &ConfigError{Op: "Invalid", Err: fmt.Errorf("%d problem(s) found in %s", len(problems), path)}
+	}
+
+	fmt.Println("Configuration file " + path + " is valid")
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateTOMLLines(t *testing.T) {
+	t.Setenv("GODADDYDDNS_CONFIG", filepath.Join(t.TempDir(), "config.toml"))
+
+	content := `version = 2
+
+[settings.daemon]
+workers = "four"
+
+[[config]]
+domain = "example.com"
+name = "home"
+ttl = 60
+key = "k"
+secret = "s"
+hooks = { pre_update = { command = "" } }
+
+[[config]]
+domain = "example.com"
+name = "home"
+ttl = "x"
+key = "k"
+secret = "s"
+
+[config.settings.http]
+timeout = 5
+`
+
+	want := []configProblem{
+		{Line: 4, Path: "settings.daemon.workers"},
+		{Line: 9, Path: "config[0].ttl"},
+		{Line: 12, Path: "config[0].hooks.pre_update.command"},
+		{Line: 14, Path: "config[1]"},
+		{Line: 17, Path: "config[1].ttl"},
+		{Line: 22, Path: "config[1].settings.http.timeout"},
+	}
+
+	problems := validateConfiguration([]byte(content))
+	if len(problems) != len(want) {
+		t.Fatalf("got problems %v, want %d", problems, len(want))
+	}
+	for i, problem := range problems {
+		if problem.Line != want[i].Line || problem.Path != want[i].Path {
+			t.Errorf("got %s, want %s at line %d", problem, want[i].Path, want[i].Line)
+		}
+	}
+}
+
+func TestValidateTypeAndValueProblems(t *testing.T) {
+	t.Setenv("GODADDYDDNS_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+
+	content := `{
+  "version": 2,
+  "config": [
+    {"domain": "example.com", "name": "home", "ttl": "600", "key": "k", "secret": "s"},
+    {"domain": "example.com", "name": "home", "ttl": 60, "key": "k", "secret": "s"}
+  ]
+}`
+
+	problems := validateConfiguration([]byte(content))
+	paths := make(map[string]bool)
+	for _, problem := range problems {
+		paths[problem.Path] = true
+	}
+	for _, path := range []string{"config[0].ttl", "config[1].ttl", "config[1]"} {
+		if !paths[path] {
+			t.Errorf("no problem reported at %s, got %v", path, problems)
+		}
+	}
+	if len(problems) != 3 {
+		t.Errorf("got problems %v, want 3", problems)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errFileLocked = errors.New("file is locked")
+
+// lockConfigFile takes an advisory lock on <file>.lock next to configuration file, so that
+// concurrent commands do not lose each other's changes. Commands hold the lock from reading
+// configuration until it is written, and release it by calling the returned function.
+// Lock held by another process is waited for upto lock_timeout of settings.
+func lockConfigFile(settings *ConfigFileSettings) (func(), error) {
+	path, err := configFilePath()
+	if err != nil {
+		return nil, err
+	}
+	path += ".lock"
+	err = os.MkdirAll(filepath.Dir(path), config_dir_perm)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, config_file_perm)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(time.Duration(settings.LockTimeout))
+	for {
+		err = tryLockFile(file)
+		if err == nil {
+			break
+		}
+		if err != errFileLocked || time.Now().After(deadline) {
+			file.Close()
+			if err == errFileLocked {
+				err = ErrConfigBusy
+			}
+			return nil, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
+
+// writeConfigFile replaces configuration file with content, keeping previous content as
+// backup. Readers see either previous or new configuration and never a partly written file.
+func writeConfigFile(content []byte, settings *ConfigFileSettings) error {
+	path, err := configFilePath()
+	if err != nil {
+		return err
+	}
+	// Symlinked configuration is replaced at its target
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	dir := filepath.Dir(path)
+
+	err = os.MkdirAll(dir, config_dir_perm)
+	if err != nil {
+		return err
+	}
+
+	perm := config_file_perm
+	previous, err := ioutil.ReadFile(path)
+	if err == nil {
+		if bytes.Equal(previous, content) {
+			return nil
+		}
+		if info, err := os.Stat(path); err == nil {
+			perm = info.Mode().Perm()
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Plain text secrets are not kept in backups once secrets are encrypted
+	if previous != nil && !plaintextBackup(previous, content) {
+		err = backupConfigFile(path, previous, *settings.Backups)
+		if err != nil {
+			return fmt.Errorf("backing up previous configuration %w", err)
+		}
+	}
+
+	return writeFileAtomic(path, content, perm)
+}
+
+// writeFileAtomic replaces file at path with content. Content is written to a temporary file
+// in the same directory, synced and renamed over the file, so that the file is never partly
+// written.
+func writeFileAtomic(path string, content []byte, perm fs.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = tmp.Chmod(perm)
+	if err == nil {
+		_, err = tmp.Write(content)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return err
+	}
+	// File is written even if rename could not be made durable
+	syncDir(dir)
+	return nil
+}
+
+// backupConfigFile saves previous content of configuration file as <file>.1.bak. Older
+// backups are shifted to <file>.2.bak and so on, keeping count of them.
+func backupConfigFile(path string, previous []byte, count int) error {
+	if count < 1 {
+		return nil
+	}
+
+	backup := func(n int) string {
+		return fmt.Sprintf("%s.%d.bak", path, n)
+	}
+
+	err := os.Remove(backup(count))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := count - 1; n >= 1; n-- {
+		err = os.Rename(backup(n), backup(n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeFileAtomic(backup(1), previous, config_file_perm)
+}
+
+// plaintextBackup reports whether previous content of configuration file has secrets in
+// plain text while content has encryption enabled. Previous content which cannot be decoded
+// is treated as having them.
+func plaintextBackup(previous, content []byte) bool {
+	config, err := decodeConfiguration(content)
+	if err != nil || config.Encryption == nil {
+		return false
+	}
+	config, err = decodeConfiguration(previous)
+	if err != nil {
+		return true
+	}
+
+	plaintext := false
+	transformSecrets(&config, func(value string) (string, error) {
+		if value != "" && !isEncrypted(value) && !isSecretReference(value) {
+			plaintext = true
+		}
+		return value, nil
+	})
+	return plaintext
+}
+
+// removeConfigBackups removes <file>.N.bak backups and <file>.vN.bak backups of migration
+// next to configuration file and its symlink target. It returns number of removed files.
+func removeConfigBackups() (int, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return 0, err
+	}
+	paths := []string{path}
+	if target, err := filepath.EvalSymlinks(path); err == nil && target != path {
+		paths = append(paths, target)
+	}
+
+	removed := 0
+	for _, path := range paths {
+		matches, err := filepath.Glob(path + ".*.bak")
+		if err != nil {
+			return removed, err
+		}
+		for _, match := range matches {
+			n := strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(match, path+"."), ".bak"), "v")
+			if _, err := strconv.Atoi(n); err != nil {
+				continue
+			}
+			err = os.Remove(match)
+			if err != nil && !os.IsNotExist(err) {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
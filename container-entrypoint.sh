@@ -2,8 +2,22 @@
 
 set -e
 
-if [ "$GD_NAME" == "" -o "$GD_DOMAIN" == "" -o "$GD_TTL" == "" -o "$GD_KEY" == "" -o "$GD_SECRET" == "" ]; then
-    echo "ERROR GD_NAME, GD_DOMAIN, GD_TTL, GD_KEY and GD_SECRET are mandatory."
+# Key and secret are passed as references so that their values do not show up in ps
+# and are not written to config.json.
+if [ "$GD_KEY_FILE" != "" ]; then
+    GD_KEY_REF="file:$GD_KEY_FILE"
+elif [ "$GD_KEY" != "" ]; then
+    GD_KEY_REF="env:GD_KEY"
+fi
+
+if [ "$GD_SECRET_FILE" != "" ]; then
+    GD_SECRET_REF="file:$GD_SECRET_FILE"
+elif [ "$GD_SECRET" != "" ]; then
+    GD_SECRET_REF="env:GD_SECRET"
+fi
+
+if [ "$GD_NAME" == "" -o "$GD_DOMAIN" == "" -o "$GD_TTL" == "" -o "$GD_KEY_REF" == "" -o "$GD_SECRET_REF" == "" ]; then
+    echo "ERROR GD_NAME, GD_DOMAIN, GD_TTL, GD_KEY (or GD_KEY_FILE) and GD_SECRET (or GD_SECRET_FILE) are mandatory."
     echo "Use --env with docker run to pass those environment variables."
     exit 1
 fi
@@ -12,10 +26,13 @@ if [ $GD_TTL -lt 600 ]; then
     echo "ERROR TTL must be greater than or equal to 600."
     exit 1
 fi
-if [ ! -f $HOME/.config/godaddy-ddns/config.json ]; then
-    /app/godaddyddns add --domain="$GD_DOMAIN" --name="$GD_NAME" --ttl=$GD_TTL --key="$GD_KEY" --secret="$GD_SECRET"
+
+# Same lookup as godaddyddns, so that configuration mounted elsewhere is found
+CONFIG_FILE="${GODADDYDDNS_CONFIG:-${XDG_CONFIG_HOME:-$HOME/.config}/godaddy-ddns/config.json}"
+if [ ! -f "$CONFIG_FILE" ]; then
+    /app/godaddyddns add --domain="$GD_DOMAIN" --name="$GD_NAME" --ttl=$GD_TTL --key="$GD_KEY_REF" --secret="$GD_SECRET_REF"
 else
     echo "Configuration already exist. Syncing the record"
-    /app/godaddyddns update --domain="$GD_DOMAIN" --name="$GD_NAME" --ttl=$GD_TTL --key="$GD_KEY" --secret="$GD_SECRET"
+    /app/godaddyddns update --domain="$GD_DOMAIN" --name="$GD_NAME" --ttl=$GD_TTL --key="$GD_KEY_REF" --secret="$GD_SECRET_REF"
 fi
-/app/godaddyddns daemon
\ No newline at end of file
+/app/godaddyddns daemon $GD_DAEMON_ARGS
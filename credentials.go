@@ -0,0 +1,276 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Credential is a GoDaddy API key and secret shared by records referring to it by profile name.
+type Credential struct {
+	Key    string `json:"key"`
+	Secret string `json:"secret"`
+}
+
+// resolveSecret returns the value of a secret reference. env:NAME is read from environment
+// variable NAME, file:/path from the file, e.g. a Docker or Kubernetes secret, and
+// keyring:NAME from the keyring of the user. Other values are returned as they are.
+func resolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		secret, ok := os.LookupEnv(name)
+		if !ok || secret == "" {
+			return "", fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, "file:"):
+		path := strings.TrimPrefix(value, "file:")
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, err.Error())
+		}
+		secret := strings.TrimSpace(string(content))
+		if secret == "" {
+			return "", fmt.Errorf("%w: %s is empty", ErrSecretNotFound, path)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, "keyring:"):
+		secret, err := keyringSecret(strings.TrimPrefix(value, "keyring:"))
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, err.Error())
+		}
+		return secret, nil
+	}
+	return value, nil
+}
+
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, "env:") || strings.HasPrefix(value, "file:") || strings.HasPrefix(value, "keyring:")
+}
+
+// resolveCredentials returns the record with key and secret of its profile, and with
+// secret references resolved.
+func resolveCredentials(config Configuration, record DNSRecord) (DNSRecord, error) {
+	if record.Profile != "" {
+		credential, ok := config.Credentials[record.Profile]
+		if !ok {
+			return record, &ConfigError{Op: "Error resolving credentials in", Err: fmt.Errorf("%w: %s", ErrProfileNotFound, record.Profile)}
+		}
+		record.Key = credential.Key
+		record.Secret = credential.Secret
+	}
+
+	var err error
+	record.Key, record.Secret, err = resolveKeyAndSecret(config.Encryption, record.Key, record.Secret)
+	return record, err
+}
+
+// resolveKeyAndSecret decrypts encrypted values and resolves secret references.
+func resolveKeyAndSecret(encryption *Encryption, key, secret string) (string, string, error) {
+	key, err := decryptSecret(encryption, key)
+	if err == nil {
+		key, err = resolveSecret(key)
+	}
+	if err != nil {
+		return "", "", &ConfigError{Op: "Error resolving key in", Err: err}
+	}
+
+	secret, err = decryptSecret(encryption, secret)
+	if err == nil {
+		secret, err = resolveSecret(secret)
+	}
+	if err != nil {
+		return "", "", &ConfigError{Op: "Error resolving secret in", Err: err}
+	}
+	return key, secret, nil
+}
+
+func maskKey(key string) string {
+	if isEncrypted(key) {
+		return "(encrypted)"
+	}
+	if isSecretReference(key) {
+		return key
+	}
+	if len(key) <= 4 {
+		return "****"
+	}
+	return key[:4] + "****"
+}
+
+func credentialsUsage() {
+	fmt.Printf("\nUsage:\n")
+	fmt.Printf("\tcredentials add|rotate|list|remove [options]\n")
+	fmt.Printf("\nadd\n")
+	fmt.Printf("\tAdd named credential profile\n")
+	fmt.Printf("\nrotate\n")
+	fmt.Printf("\tReplace key and secret of a profile. All records using the profile use new key from next poll\n")
+	fmt.Printf("\nlist\n")
+	fmt.Printf("\tList credential profiles\n")
+	fmt.Printf("\nremove\n")
+	fmt.Printf("\tRemove a credential profile which is not used by any record\n")
+	fmt.Printf("\n\nExamples\n")
+	fmt.Printf("\tgodaddyddns credentials add --profile='personal' --key='kEyGeneratedFr0mG0DaddY' --secret='s3cRe7GeneratedFr0mG0DaddY'\n")
+	fmt.Printf("\tgodaddyddns add --domain='example.com' --name='myweb' --profile='personal'\n")
+	fmt.Printf("\tgodaddyddns credentials rotate --profile='personal' --key='n3wKeY' --secret='n3wS3cReT'\n")
+	fmt.Printf("\tgodaddyddns credentials add --profile='work' --key='env:GD_KEY' --secret='file:/run/secrets/gd_secret'\n")
+}
+
+func credentialsCmd(args []string, settings Settings) {
+	if len(args) < 1 {
+		credentialsUsage()
+		os.Exit(ExitUsage)
+	}
+
+	action := args[0]
+	cmd := flag.NewFlagSet("credentials "+action, flag.ExitOnError)
+	profile := cmd.String("profile", "", "Name of credential profile e.g. personal")
+	var key, secret *string
+	if action == "add" || action == "rotate" {
+		key = cmd.String("key", "", "Key value generated from godaddy developer console")
+		secret = cmd.String("secret", "", "Secret value generated from godaddy developer console")
+	}
+
+	var err error
+	switch action {
+	case "add", "rotate":
+		cmd.Parse(args[1:])
+		if *profile == "" || *key == "" || *secret == "" {
+			fmt.Println("ERROR profile, key and secret are mandatory")
+			fmt.Printf("\nUsage of credentials %s:\n", action)
+			cmd.PrintDefaults()
+			os.Exit(ExitUsage)
+		}
+		err = setCredential(settings, *profile, Credential{Key: *key, Secret: *secret}, action == "rotate")
+	case "remove":
+		cmd.Parse(args[1:])
+		if *profile == "" {
+			fmt.Println("ERROR profile is mandatory")
+			fmt.Printf("\nUsage of credentials %s:\n", action)
+			cmd.PrintDefaults()
+			os.Exit(ExitUsage)
+		}
+		err = removeCredential(settings, *profile)
+	case "list":
+		err = listCredentials()
+	default:
+		credentialsUsage()
+		os.Exit(ExitUsage)
+	}
+
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to "+action+" credentials. "+err.Error())
+		os.Exit(exitCode(err))
+	}
+}
+
+// setCredential adds the profile, or replaces key and secret of existing profile if rotate is true.
+func setCredential(settings Settings, profile string, credential Credential, rotate bool) error {
+	unlock, err := lockConfigFile(settings.ConfigFile)
+	if err != nil {
+		return &ConfigError{Op: "Error locking", Err: err}
+	}
+	defer unlock()
+
+	config, err := loadConfiguration()
+	if err != nil {
+		return err
+	}
+
+	_, exists := config.Credentials[profile]
+	if rotate && !exists {
+		return &ConfigError{Op: "Error rotating credentials in", Err: fmt.Errorf("%w: %s", ErrProfileNotFound, profile)}
+	}
+	if !rotate && exists {
+		return &ConfigError{Op: "Error adding credentials to", Err: fmt.Errorf("%w: %s", ErrProfileExists, profile)}
+	}
+
+	if config.Credentials == nil {
+		config.Credentials = make(map[string]Credential)
+	}
+	config.Credentials[profile] = credential
+
+	err = saveConfiguration(config, settings.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	if rotate {
+		GoDaddyDDNSLogger(InformationLog, "", "", "Credentials of profile "+profile+" rotated (key: ****, secret: ****)")
+	} else {
+		GoDaddyDDNSLogger(InformationLog, "", "", "Credential profile "+profile+" added (key: ****, secret: ****)")
+	}
+	return nil
+}
+
+func removeCredential(settings Settings, profile string) error {
+	unlock, err := lockConfigFile(settings.ConfigFile)
+	if err != nil {
+		return &ConfigError{Op: "Error locking", Err: err}
+	}
+	defer unlock()
+
+	config, err := loadConfiguration()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := config.Credentials[profile]; !ok {
+		return &ConfigError{Op: "Error removing credentials from", Err: fmt.Errorf("%w: %s", ErrProfileNotFound, profile)}
+	}
+	for _, record := range config.Config {
+		if record.Profile == profile {
+			return &ConfigError{Op: "Error removing credentials from", Err: fmt.Errorf("%w: %s is used by %s", ErrProfileInUse, profile, recordId(record))}
+		}
+	}
+
+	delete(config.Credentials, profile)
+	err = saveConfiguration(config, settings.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	GoDaddyDDNSLogger(InformationLog, "", "", "Credential profile "+profile+" removed")
+	return nil
+}
+
+func listCredentials() error {
+	config, err := loadConfiguration()
+	if err != nil {
+		return err
+	}
+
+	if len(config.Credentials) == 0 {
+		fmt.Println("No credential profile configured.")
+		return nil
+	}
+
+	var profiles []string
+	for profile := range config.Credentials {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Profile", "Key", "Records"})
+
+	for _, profile := range profiles {
+		count := 0
+		for _, record := range config.Config {
+			if record.Profile == profile {
+				count++
+			}
+		}
+		t.AppendRow(table.Row{profile, maskKey(config.Credentials[profile].Key), count})
+	}
+	t.Render()
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// authoritativeNameservers returns host:port of name servers of the domain.
+// GODADDYDDNS_NAMESERVERS (comma separated host[:port]) overrides the NS lookup,
+// e.g. to use a local DNS server in tests.
+func authoritativeNameservers(domain string) ([]string, error) {
+	if override := os.Getenv("GODADDYDDNS_NAMESERVERS"); override != "" {
+		var servers []string
+		for _, server := range strings.Split(override, ",") {
+			server = strings.TrimSpace(server)
+			if server == "" {
+				continue
+			}
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(server, "53")
+			}
+			servers = append(servers, server)
+		}
+		return servers, nil
+	}
+
+	records, err := net.LookupNS(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []string
+	for _, ns := range records {
+		servers = append(servers, net.JoinHostPort(strings.TrimSuffix(ns.Host, "."), "53"))
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no name server found for %s", domain)
+	}
+	return servers, nil
+}
+
+// queryAuthoritative asks the name server directly for records of fqdn without recursion.
+func queryAuthoritative(server, fqdn string, qtype uint16, timeout time.Duration) ([]dns.RR, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(fqdn), qtype)
+	msg.RecursionDesired = false
+
+	client := &dns.Client{Timeout: timeout}
+	response, _, err := client.Exchange(msg, server)
+	if err != nil {
+		return nil, err
+	}
+	if response.Truncated {
+		client.Net = "tcp"
+		response, _, err = client.Exchange(msg, server)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%s answered %s", server, dns.RcodeToString[response.Rcode])
+	}
+
+	var answers []dns.RR
+	for _, rr := range response.Answer {
+		if rr.Header().Rrtype == qtype {
+			answers = append(answers, rr)
+		}
+	}
+	return answers, nil
+}
+
+// hasTXT reports if the name server serves value in TXT records of fqdn.
+func hasTXT(server, fqdn, value string, timeout time.Duration) (bool, error) {
+	answers, err := queryAuthoritative(server, fqdn, dns.TypeTXT, timeout)
+	if err != nil {
+		return false, err
+	}
+	for _, rr := range answers {
+		if strings.Join(rr.(*dns.TXT).Txt, "") == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hasA reports if the name server serves ip in A records of fqdn.
+func hasA(server, fqdn, ip string, timeout time.Duration) (bool, error) {
+	answers, err := queryAuthoritative(server, fqdn, dns.TypeA, timeout)
+	if err != nil {
+		return false, err
+	}
+	want := net.ParseIP(ip)
+	for _, rr := range answers {
+		if rr.(*dns.A).A.Equal(want) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// waitForTXT waits until all authoritative name servers of the domain serve value in TXT records of fqdn.
+func waitForTXT(domain, fqdn, value string, daemon *DaemonSettings) error {
+	return waitForAuthoritative(domain, daemon, func(server string) (bool, error) {
+		return hasTXT(server, fqdn, value, time.Duration(daemon.DNSTimeout))
+	})
+}
+
+// waitForA waits until all authoritative name servers of the domain serve ip in A records of fqdn.
+func waitForA(domain, fqdn, ip string, daemon *DaemonSettings) error {
+	return waitForAuthoritative(domain, daemon, func(server string) (bool, error) {
+		return hasA(server, fqdn, ip, time.Duration(daemon.DNSTimeout))
+	})
+}
+
+// waitForAuthoritative polls all authoritative name servers of the domain every
+// propagation_poll until check succeeds on each of them or propagation_timeout expires.
+func waitForAuthoritative(domain string, daemon *DaemonSettings, check func(server string) (bool, error)) error {
+	servers, err := authoritativeNameservers(domain)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(daemon.PropagationTimeout)
+	deadline := time.Now().Add(timeout)
+	pending := servers
+
+	for {
+		var stillPending []string
+		for _, server := range pending {
+			found, err := check(server)
+			if err != nil || !found {
+				stillPending = append(stillPending, server)
+			}
+		}
+		pending = stillPending
+
+		if len(pending) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w after %s: %s", ErrNotPropagated, timeout, strings.Join(pending, ", "))
+		}
+		time.Sleep(time.Duration(daemon.PropagationPoll))
+	}
+}
+
+// verifyPropagation waits until the new ip of the record is served by all authoritative
+// name servers and records the outcome in daemon metrics.
+func verifyPropagation(record DNSRecord, ip string, daemon *DaemonSettings) {
+	fqdn := recordFqdn(record)
+	labels := metricLabels("domain", record.Domain, "name", record.Name)
+
+	start := time.Now()
+	err := waitForA(record.Domain, fqdn, ip, daemon)
+	if err != nil {
+		result := "error"
+		if errors.Is(err, ErrNotPropagated) {
+			result = "timeout"
+		}
+		daemonMetrics.add(metricPropagationChecks, labels+","+metricLabels("result", result), 1)
+		GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "New IP "+ip+" is not served by all authoritative name servers. "+err.Error())
+		return
+	}
+
+	elapsed := time.Since(start)
+	daemonMetrics.add(metricPropagationChecks, labels+","+metricLabels("result", "live"), 1)
+	daemonMetrics.set(metricPropagationSeconds, labels, elapsed.Seconds())
+	GoDaddyDDNSLogger(InformationLog, record.Name, record.Domain, "New IP "+ip+" is live on all authoritative name servers after "+elapsed.Round(time.Second).String())
+}
+
+func recordFqdn(record DNSRecord) string {
+	if record.Name == "@" || record.Name == "" {
+		return record.Domain
+	}
+	return record.Name + "." + record.Domain
+}
+
+// lookupAuthoritativeA returns A records of fqdn as served by the authoritative name servers.
+// It fails if any name server does not answer, the record is missing or name servers
+// disagree, so that the caller can fall back to the API.
+func lookupAuthoritativeA(domain, fqdn string, timeout time.Duration) ([]godaddy.GodaddyRecordBody, error) {
+	servers, err := authoritativeNameservers(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []godaddy.GodaddyRecordBody
+	for i, server := range servers {
+		answers, err := queryAuthoritative(server, fqdn, dns.TypeA, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if len(answers) == 0 {
+			return nil, fmt.Errorf("%s has no A record for %s", server, fqdn)
+		}
+
+		var serverRecords []godaddy.GodaddyRecordBody
+		for _, rr := range answers {
+			a := rr.(*dns.A)
+			serverRecords = append(serverRecords, godaddy.GodaddyRecordBody{Data: a.A.String(), TTL: int(a.Hdr.Ttl)})
+		}
+
+		if i == 0 {
+			records = serverRecords
+		} else if !sameRecords(records, serverRecords, domain) {
+			return nil, fmt.Errorf("name servers disagree on %s", fqdn)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// testNameserver is an authoritative DNS server on 127.0.0.1 serving A records
+// which tests can change while it runs.
+type testNameserver struct {
+	mu      sync.Mutex
+	records map[string]string // fqdn to IP
+	queries int
+}
+
+func (ns *testNameserver) set(fqdn, ip string) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.records[dns.Fqdn(fqdn)] = ip
+}
+
+func (ns *testNameserver) queryCount() int {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return ns.queries
+}
+
+func (ns *testNameserver) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.queries++
+
+	msg := new(dns.Msg)
+	msg.SetReply(r)
+	msg.Authoritative = true
+	question := r.Question[0]
+	if ip, ok := ns.records[question.Name]; ok && question.Qtype == dns.TypeA {
+		msg.Answer = append(msg.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 600},
+			A:   net.ParseIP(ip),
+		})
+	} else if !ok {
+		msg.Rcode = dns.RcodeNameError
+	}
+	w.WriteMsg(msg)
+}
+
+// startNameserver runs a test name server and makes it the authoritative name server of all domains.
+func startNameserver(t *testing.T) *testNameserver {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := &testNameserver{records: make(map[string]string)}
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: conn, Handler: ns, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+
+	t.Setenv("GODADDYDDNS_NAMESERVERS", conn.LocalAddr().String())
+	return ns
+}
+
+// propagationSettings returns daemon settings polling the test name server quickly.
+func propagationSettings(timeout time.Duration) *DaemonSettings {
+	return defaultSettings().overlay(&Settings{Daemon: &DaemonSettings{
+		PropagationTimeout: Duration(timeout),
+		PropagationPoll:    Duration(20 * time.Millisecond),
+		DNSTimeout:         Duration(time.Second),
+	}}).Daemon
+}
+
+func TestWaitForA(t *testing.T) {
+	ns := startNameserver(t)
+	ns.set("home.example.com", "203.0.113.1")
+
+	// Name server picks up the update while we wait
+	time.AfterFunc(100*time.Millisecond, func() { ns.set("home.example.com", "203.0.113.2") })
+
+	err := waitForA("example.com", "home.example.com", "203.0.113.2", propagationSettings(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queries := ns.queryCount(); queries < 2 {
+		t.Errorf("got %d queries, want name server to be polled until it serves the IP", queries)
+	}
+}
+
+func TestWaitForATimeout(t *testing.T) {
+	ns := startNameserver(t)
+	ns.set("home.example.com", "203.0.113.1")
+
+	err := waitForA("example.com", "home.example.com", "203.0.113.2", propagationSettings(200*time.Millisecond))
+	if !errors.Is(err, ErrNotPropagated) {
+		t.Fatalf("got %v, want ErrNotPropagated", err)
+	}
+}
+
+func TestVerifyPropagation(t *testing.T) {
+	ns := startNameserver(t)
+	record := DNSRecord{Domain: "example.com", Name: "home"}
+	labels := metricLabels("domain", "example.com", "name", "home")
+
+	ns.set("home.example.com", "203.0.113.1")
+	verifyPropagation(record, "203.0.113.2", propagationSettings(200*time.Millisecond))
+	if got := daemonMetrics.values[metricPropagationChecks][labels+","+metricLabels("result", "timeout")]; got != 1 {
+		t.Errorf("got %v timeout checks, want 1", got)
+	}
+
+	ns.set("home.example.com", "203.0.113.2")
+	verifyPropagation(record, "203.0.113.2", propagationSettings(5*time.Second))
+	if got := daemonMetrics.values[metricPropagationChecks][labels+","+metricLabels("result", "live")]; got != 1 {
+		t.Errorf("got %v live checks, want 1", got)
+	}
+	if _, ok := daemonMetrics.values[metricPropagationSeconds][labels]; !ok {
+		t.Errorf("propagation seconds not recorded")
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// Encrypted secrets are stored as enc:<base64>. With scrypt method, the key is derived
+// from a passphrase and values are sealed with AES-256-GCM. With age method, values are
+// encrypted to an age recipient and decrypted with its identity file.
+const (
+	EncryptionScrypt string = "scrypt"
+	EncryptionAge    string = "age"
+
+	encryptedPrefix string = "enc:"
+
+	scryptN      int = 1 << 15
+	scryptR      int = 8
+	scryptP      int = 1
+	scryptKeyLen int = 32
+)
+
+// Encryption describes how secrets in configuration are encrypted. Salt is used by scrypt
+// method and recipient by age method.
+type Encryption struct {
+	Method    string `json:"method"`
+	Salt      string `json:"salt,omitempty"`
+	Recipient string `json:"recipient,omitempty"`
+}
+
+var (
+	unlockMutex  sync.Mutex
+	unlockedKeys = make(map[string][]byte)
+	identities   []age.Identity
+)
+
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// passphrase returns passphrase from GODADDYDDNS_PASSPHRASE, file in GODADDYDDNS_PASSPHRASE_FILE
+// or terminal prompt, in that order.
+func passphrase(confirm bool) (string, error) {
+	if value := os.Getenv("GODADDYDDNS_PASSPHRASE"); value != "" {
+		return value, nil
+	}
+	if file := os.Getenv("GODADDYDDNS_PASSPHRASE_FILE"); file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrConfigLocked, err.Error())
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("%w: set GODADDYDDNS_PASSPHRASE or GODADDYDDNS_PASSPHRASE_FILE", ErrConfigLocked)
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	value, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm passphrase: ")
+		again, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Equal(value, again) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	if len(value) == 0 {
+		return "", errors.New("passphrase must not be empty")
+	}
+	return string(value), nil
+}
+
+// scryptKey derives the AES key from passphrase. Key is derived once per process.
+func scryptKey(encryption *Encryption, confirm bool) ([]byte, error) {
+	unlockMutex.Lock()
+	defer unlockMutex.Unlock()
+
+	if key, ok := unlockedKeys[encryption.Salt]; ok {
+		return key, nil
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(encryption.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption salt %w", err)
+	}
+	secret, err := passphrase(confirm)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(secret), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	unlockedKeys[encryption.Salt] = key
+	return key, nil
+}
+
+// ageIdentities reads identities from file in GODADDYDDNS_IDENTITY_FILE.
+func ageIdentities() ([]age.Identity, error) {
+	unlockMutex.Lock()
+	defer unlockMutex.Unlock()
+
+	if identities != nil {
+		return identities, nil
+	}
+
+	file := os.Getenv("GODADDYDDNS_IDENTITY_FILE")
+	if file == "" {
+		return nil, fmt.Errorf("%w: set GODADDYDDNS_IDENTITY_FILE", ErrConfigLocked)
+	}
+	content, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrConfigLocked, err.Error())
+	}
+	defer content.Close()
+
+	parsed, err := age.ParseIdentities(content)
+	if err != nil {
+		return nil, err
+	}
+	identities = parsed
+	return identities, nil
+}
+
+func sealGCM(key []byte, plaintext string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+func openGCM(key []byte, ciphertext []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+	plaintext, err := gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: wrong passphrase or corrupted value", ErrConfigLocked)
+	}
+	return string(plaintext), nil
+}
+
+// encryptSecret encrypts value as configured. Empty values and secret references are not encrypted.
+func encryptSecret(encryption *Encryption, value string) (string, error) {
+	if encryption == nil || value == "" || isEncrypted(value) || isSecretReference(value) {
+		return value, nil
+	}
+
+	switch encryption.Method {
+	case EncryptionScrypt:
+		key, err := scryptKey(encryption, false)
+		if err != nil {
+			return "", err
+		}
+		sealed, err := sealGCM(key, value)
+		if err != nil {
+			return "", err
+		}
+		return encryptedPrefix + sealed, nil
+	case EncryptionAge:
+		recipient, err := age.ParseX25519Recipient(encryption.Recipient)
+		if err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		w, err := age.Encrypt(&out, recipient)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(w, value); err != nil {
+			return "", err
+		}
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+		return encryptedPrefix + base64.StdEncoding.EncodeToString(out.Bytes()), nil
+	}
+	return "", fmt.Errorf("unknown encryption method %q", encryption.Method)
+}
+
+// decryptSecret decrypts enc: values. Other values are returned as they are.
+func decryptSecret(encryption *Encryption, value string) (string, error) {
+	if !isEncrypted(value) {
+		return value, nil
+	}
+	if encryption == nil {
+		return "", errors.New("encrypted value found but configuration has no encryption settings")
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value %w", err)
+	}
+
+	switch encryption.Method {
+	case EncryptionScrypt:
+		key, err := scryptKey(encryption, false)
+		if err != nil {
+			return "", err
+		}
+		return openGCM(key, ciphertext)
+	case EncryptionAge:
+		ids, err := ageIdentities()
+		if err != nil {
+			return "", err
+		}
+		r, err := age.Decrypt(bytes.NewReader(ciphertext), ids...)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrConfigLocked, err.Error())
+		}
+		plaintext, err := ioutil.ReadAll(r)
+		return string(plaintext), err
+	}
+	return "", fmt.Errorf("unknown encryption method %q", encryption.Method)
+}
+
+// copySecrets copies records and profiles of config, so that secrets can be transformed
+// without changing the configuration config was copied from.
+func copySecrets(config *Configuration) {
+	config.Config = append([]DNSRecord(nil), config.Config...)
+	credentials := make(map[string]Credential, len(config.Credentials))
+	for profile, credential := range config.Credentials {
+		credentials[profile] = credential
+	}
+	config.Credentials = credentials
+}
+
+// transformSecrets applies fn to key and secret of all records and credential profiles.
+func transformSecrets(config *Configuration, fn func(string) (string, error)) error {
+	var err error
+	for i := range config.Config {
+		if config.Config[i].Key, err = fn(config.Config[i].Key); err != nil {
+			return err
+		}
+		if config.Config[i].Secret, err = fn(config.Config[i].Secret); err != nil {
+			return err
+		}
+	}
+	for profile, credential := range config.Credentials {
+		if credential.Key, err = fn(credential.Key); err != nil {
+			return err
+		}
+		if credential.Secret, err = fn(credential.Secret); err != nil {
+			return err
+		}
+		config.Credentials[profile] = credential
+	}
+	return nil
+}
+
+// encryptConfiguration enables encryption of secrets in configuration with method.
+func encryptConfiguration(settings Settings, method, recipient string) error {
+	unlock, err := lockConfigFile(settings.ConfigFile)
+	if err != nil {
+		return &ConfigError{Op: "Error locking", Err: err}
+	}
+	defer unlock()
+
+	config, err := loadConfiguration()
+	if err != nil {
+		return err
+	}
+	if config.Encryption != nil {
+		return &ConfigError{Op: "Error encrypting", Err: errors.New("configuration is already encrypted")}
+	}
+
+	encryption := &Encryption{Method: method}
+	switch method {
+	case EncryptionScrypt:
+		salt := make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return err
+		}
+		encryption.Salt = base64.StdEncoding.EncodeToString(salt)
+		// Ask passphrase twice before first use
+		if _, err := scryptKey(encryption, true); err != nil {
+			return err
+		}
+	case EncryptionAge:
+		if recipient == "" {
+			ids, err := ageIdentities()
+			if err != nil {
+				return err
+			}
+			identity, ok := ids[0].(*age.X25519Identity)
+			if !ok {
+				return errors.New("identity file has no X25519 identity. pass --recipient")
+			}
+			recipient = identity.Recipient().String()
+		}
+		if _, err := age.ParseX25519Recipient(recipient); err != nil {
+			return err
+		}
+		encryption.Recipient = recipient
+	default:
+		return fmt.Errorf("unknown encryption method %q", method)
+	}
+
+	config.Encryption = encryption
+	err = saveConfiguration(config, settings.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	GoDaddyDDNSLogger(InformationLog, "", "", "Secrets in configuration encrypted using "+method)
+
+	// Backups of configuration have secrets in plain text
+	removed, err := removeConfigBackups()
+	if err != nil {
+		return &ConfigError{Op: "Error removing backups", Err: err}
+	}
+	if removed != 0 {
+		GoDaddyDDNSLogger(InformationLog, "", "", fmt.Sprintf("%d backup(s) of configuration with plain text secrets removed", removed))
+	}
+	return nil
+}
+
+// decryptConfiguration stores secrets in configuration as plain text again.
+func decryptConfiguration(settings Settings) error {
+	unlock, err := lockConfigFile(settings.ConfigFile)
+	if err != nil {
+		return &ConfigError{Op: "Error locking", Err: err}
+	}
+	defer unlock()
+
+	config, err := loadConfiguration()
+	if err != nil {
+		return err
+	}
+	if config.Encryption == nil {
+		return &ConfigError{Op: "Error decrypting", Err: errors.New("configuration is not encrypted")}
+	}
+
+	err = transformSecrets(&config, func(value string) (string, error) {
+		return decryptSecret(config.Encryption, value)
+	})
+	if err != nil {
+		return &ConfigError{Op: "Error decrypting", Err: err}
+	}
+
+	config.Encryption = nil
+	err = saveConfiguration(config, settings.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	GoDaddyDDNSLogger(InformationLog, "", "", "Secrets in configuration decrypted")
+	return nil
+}
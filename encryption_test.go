@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// forgetUnlocked drops keys and identities cached by earlier tests.
+func forgetUnlocked() {
+	unlockMutex.Lock()
+	defer unlockMutex.Unlock()
+	unlockedKeys = make(map[string][]byte)
+	identities = nil
+}
+
+func scryptEncryption(t *testing.T, passphrase string) *Encryption {
+	forgetUnlocked()
+	t.Setenv("GODADDYDDNS_PASSPHRASE", passphrase)
+	return &Encryption{Method: EncryptionScrypt, Salt: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))}
+}
+
+// ageEncryption returns age encryption to a new identity, and makes the identity
+// file of identity the one used to decrypt.
+func ageEncryption(t *testing.T) (*Encryption, *age.X25519Identity) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	useIdentity(t, identity)
+	return &Encryption{Method: EncryptionAge, Recipient: identity.Recipient().String()}, identity
+}
+
+func useIdentity(t *testing.T, identity *age.X25519Identity) {
+	forgetUnlocked()
+	file := filepath.Join(t.TempDir(), "identity.txt")
+	if err := ioutil.WriteFile(file, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GODADDYDDNS_IDENTITY_FILE", file)
+}
+
+func TestScryptRoundTrip(t *testing.T) {
+	encryption := scryptEncryption(t, "correct horse")
+
+	first, err := encryptSecret(encryption, "s3cRe7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := encryptSecret(encryption, "s3cRe7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(first) || strings.Contains(first, "s3cRe7") {
+		t.Fatalf("secret not encrypted: %s", first)
+	}
+	if first == second {
+		t.Errorf("same secret encrypted twice gives same value, nonce is not random")
+	}
+
+	plaintext, err := decryptSecret(encryption, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "s3cRe7" {
+		t.Errorf("got %q, want s3cRe7", plaintext)
+	}
+}
+
+func TestScryptWrongPassphrase(t *testing.T) {
+	encryption := scryptEncryption(t, "correct horse")
+	value, err := encryptSecret(encryption, "s3cRe7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryption = scryptEncryption(t, "battery staple")
+	_, err = decryptSecret(encryption, value)
+	if !errors.Is(err, ErrConfigLocked) {
+		t.Errorf("got %v, want ErrConfigLocked", err)
+	}
+}
+
+func TestAgeRoundTrip(t *testing.T) {
+	encryption, _ := ageEncryption(t)
+
+	value, err := encryptSecret(encryption, "s3cRe7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(value) {
+		t.Fatalf("secret not encrypted: %s", value)
+	}
+
+	plaintext, err := decryptSecret(encryption, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "s3cRe7" {
+		t.Errorf("got %q, want s3cRe7", plaintext)
+	}
+}
+
+func TestAgeWrongIdentity(t *testing.T) {
+	encryption, _ := ageEncryption(t)
+	value, err := encryptSecret(encryption, "s3cRe7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	useIdentity(t, other)
+	_, err = decryptSecret(encryption, value)
+	if !errors.Is(err, ErrConfigLocked) {
+		t.Errorf("got %v, want ErrConfigLocked", err)
+	}
+}
+
+func TestEncryptSecretKeepsReferences(t *testing.T) {
+	encryption := scryptEncryption(t, "correct horse")
+
+	for _, value := range []string{"", "env:GD_SECRET", "file:/run/secrets/gd", "enc:AAAA"} {
+		got, err := encryptSecret(encryption, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != value {
+			t.Errorf("encryptSecret(%q) = %q, want value unchanged", value, got)
+		}
+	}
+}
+
+func TestEncryptDecryptConfiguration(t *testing.T) {
+	t.Setenv("GODADDYDDNS_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	scryptEncryption(t, "correct horse")
+
+	settings := defaultSettings()
+	err := saveConfiguration(Configuration{
+		Config: []DNSRecord{{Domain: "example.com", Name: "home", TTL: 600, Key: "recordKey", Secret: "recordSecret"}},
+		Credentials: map[string]Credential{
+			"work": {Key: "profileKey", Secret: "profileSecret"},
+		},
+	}, settings.ConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = encryptConfiguration(settings, EncryptionScrypt, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := readConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"recordKey", "recordSecret", "profileKey", "profileSecret"} {
+		if strings.Contains(string(content), secret) {
+			t.Errorf("%s is stored as plain text after config encrypt", secret)
+		}
+	}
+
+	// Passphrase is asked again by a new process
+	forgetUnlocked()
+	config, err := loadConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := resolveCredentials(config, config.Config[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Key != "recordKey" || record.Secret != "recordSecret" {
+		t.Errorf("got key %q and secret %q after decryption", record.Key, record.Secret)
+	}
+
+	err = decryptConfiguration(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err = loadConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Encryption != nil || config.Config[0].Secret != "recordSecret" || config.Credentials["work"].Secret != "profileSecret" {
+		t.Errorf("configuration not decrypted: %+v", config)
+	}
+}
+
+func TestEncryptRemovesPlaintextBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	t.Setenv("GODADDYDDNS_CONFIG", path)
+	scryptEncryption(t, "correct horse")
+
+	settings := defaultSettings()
+	for _, secret := range []string{"oldSecret", "recordSecret"} {
+		err := saveConfiguration(Configuration{
+			Config: []DNSRecord{{Domain: "example.com", Name: "home", TTL: 600, Key: "recordKey", Secret: secret}},
+		}, settings.ConfigFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(path+".v1.bak", []byte(`{"config":[{"secret":"v1Secret"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := encryptConfiguration(settings, EncryptionScrypt, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Backups written after encryption have encrypted secrets
+	err = saveConfiguration(Configuration{
+		Encryption: &Encryption{Method: EncryptionScrypt, Salt: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))},
+		Config:     []DNSRecord{{Domain: "example.com", Name: "home", TTL: 60, Key: "recordKey", Secret: "recordSecret"}},
+	}, settings.ConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(path + "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups := 0
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{"recordKey", "recordSecret", "oldSecret", "v1Secret"} {
+			if strings.Contains(string(content), secret) {
+				t.Errorf("%s is stored as plain text in %s after config encrypt", secret, filepath.Base(file))
+			}
+		}
+		if strings.HasSuffix(file, ".bak") {
+			backups++
+		}
+	}
+	if backups != 1 {
+		t.Errorf("got %d backups, want 1 of encrypted configuration", backups)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// Exit codes of the CLI. Each error type maps to its own exit code so that
+// scripts can tell authentication failure from network failure.
+const (
+	ExitOK          int = 0
+	ExitError       int = 1
+	ExitUsage       int = 2
+	ExitConfig      int = 3
+	ExitAuth        int = 4
+	ExitNotFound    int = 5
+	ExitRateLimit   int = 6
+	ExitNetwork     int = 7
+	ExitIPDetection int = 8
+)
+
+var (
+	ErrRecordExists        = errors.New("record already exist")
+	ErrRecordNotFound      = errors.New("record doesnot exist")
+	ErrNoRecords           = errors.New("no record exist")
+	ErrRecordLimit         = errors.New("reached record limit")
+	ErrUpdateVetoed        = errors.New("update vetoed by pre-update hook")
+	ErrNoCredentials       = errors.New("no key and secret found for the domain. pass --key and --secret")
+	ErrNotPropagated       = errors.New("timed out waiting for authoritative name servers")
+	ErrProfileNotFound     = errors.New("credential profile doesnot exist")
+	ErrProfileExists       = errors.New("credential profile already exist")
+	ErrProfileInUse        = errors.New("credential profile is in use")
+	ErrSecretNotFound      = errors.New("secret reference cannot be resolved")
+	ErrConfigLocked        = errors.New("encrypted configuration cannot be unlocked")
+	ErrConfigBusy          = errors.New("configuration is being changed by another godaddy-ddns process")
+	ErrDomainNotActive     = errors.New("domain is not active")
+	ErrExternalNameservers = errors.New("domain does not use GoDaddy name servers")
+	ErrNoConfigDir         = errors.New("home directory is unknown. set --config, GODADDYDDNS_CONFIG, XDG_CONFIG_HOME or HOME")
+)
+
+// ConfigError is returned for failures reading, parsing or writing configuration.
+type ConfigError struct {
+	Op  string
+	Err error
+}
+
+func (err *ConfigError) Error() string {
+	return err.Op + " configuration, " + err.Err.Error()
+}
+
+func (err *ConfigError) Unwrap() error { return err.Err }
+
+// IPDetectionError is returned when public IP of the server could not be detected.
+type IPDetectionError struct {
+	Err error
+}
+
+func (err *IPDetectionError) Error() string {
+	return "failed to detect public IP, " + err.Err.Error()
+}
+
+func (err *IPDetectionError) Unwrap() error { return err.Err }
+
+// HookError is returned when a hook command fails or times out.
+type HookError struct {
+	Stage    string
+	ExitCode int
+	Err      error
+}
+
+func (err *HookError) Error() string {
+	return err.Stage + " hook " + err.Err.Error()
+}
+
+func (err *HookError) Unwrap() error { return err.Err }
+
+// exitCode maps error to CLI exit code.
+func exitCode(err error) int {
+	var (
+		authErr      *godaddy.AuthError
+		notFoundErr  *godaddy.NotFoundError
+		rateLimitErr *godaddy.RateLimitError
+		networkErr   *godaddy.NetworkError
+		configErr    *ConfigError
+		ipErr        *IPDetectionError
+	)
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &authErr):
+		return ExitAuth
+	case errors.As(err, &notFoundErr),
+		errors.Is(err, ErrDomainNotActive),
+		errors.Is(err, ErrExternalNameservers):
+		return ExitNotFound
+	case errors.As(err, &rateLimitErr):
+		return ExitRateLimit
+	case errors.As(err, &networkErr):
+		return ExitNetwork
+	case errors.As(err, &ipErr):
+		return ExitIPDetection
+	case errors.As(err, &configErr),
+		errors.Is(err, ErrRecordExists),
+		errors.Is(err, ErrRecordNotFound),
+		errors.Is(err, ErrNoRecords),
+		errors.Is(err, ErrRecordLimit),
+		errors.Is(err, ErrNoConfigDir):
+		return ExitConfig
+	}
+	return ExitError
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	default_failure_threshold  int = 3
+	default_recovery_threshold int = 3
+	default_check_timeout      int = 5
+)
+
+// HealthCheck checks if a server is serving. {ip} in target is replaced with the IP
+// being checked, e.g. http://{ip}:8080/health or {ip}:443.
+type HealthCheck struct {
+	Type         string `json:"type"` // http or tcp
+	Target       string `json:"target"`
+	Timeout      int    `json:"timeout,omitempty"`       // Seconds
+	ExpectStatus int    `json:"expect_status,omitempty"` // Any 2xx if not set
+}
+
+// Failover switches the record from public IP of this server to a backup IP when
+// the health check of this server fails, and back when it recovers.
+type Failover struct {
+	Backups           []string    `json:"backups"`
+	Check             HealthCheck `json:"check"`
+	FailureThreshold  int         `json:"failure_threshold,omitempty"`
+	RecoveryThreshold int         `json:"recovery_threshold,omitempty"`
+}
+
+func (f *Failover) validate() error {
+	if len(f.Backups) == 0 {
+		return fmt.Errorf("failover needs at least one backup IP")
+	}
+	for _, backup := range f.Backups {
+		if ip := net.ParseIP(backup); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("backup %q is not an IPv4 address", backup)
+		}
+	}
+	if f.Check.Type != "http" && f.Check.Type != "tcp" {
+		return fmt.Errorf("check type must be http or tcp")
+	}
+	if f.Check.Target == "" {
+		return fmt.Errorf("check target is mandatory")
+	}
+	return nil
+}
+
+// runHealthCheck checks the server at ip. It returns nil if the server is healthy.
+func runHealthCheck(check HealthCheck, ip string) error {
+	target := strings.ReplaceAll(check.Target, "{ip}", ip)
+	timeout := time.Duration(check.Timeout) * time.Second
+	if check.Timeout <= 0 {
+		timeout = time.Duration(default_check_timeout) * time.Second
+	}
+
+	if check.Type == "tcp" {
+		conn, err := net.DialTimeout("tcp", target, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	client := &http.Client{Timeout: timeout}
+	response, err := client.Get(target)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	if check.ExpectStatus != 0 && response.StatusCode != check.ExpectStatus {
+		return fmt.Errorf("%s returned status %d, expected %d", target, response.StatusCode, check.ExpectStatus)
+	}
+	if check.ExpectStatus == 0 && (response.StatusCode < 200 || response.StatusCode > 299) {
+		return fmt.Errorf("%s returned status %d", target, response.StatusCode)
+	}
+	return nil
+}
+
+// failoverHealth tracks consecutive check results of a record in daemon.
+type failoverHealth struct {
+	failures  int
+	successes int
+	active    bool // Record points to backup IP
+	backup    string
+}
+
+type failoverTracker struct {
+	mu     sync.Mutex
+	states map[string]*failoverHealth
+}
+
+func newFailoverTracker() *failoverTracker {
+	return &failoverTracker{states: make(map[string]*failoverHealth)}
+}
+
+// backupIP returns the first backup which passes the health check. If the check target
+// does not depend on the IP, backups cannot be checked and first backup is returned.
+func backupIP(record DNSRecord, failover *Failover) string {
+	if !strings.Contains(failover.Check.Target, "{ip}") {
+		GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "Backup IPs cannot be health checked as check target has no {ip}. Using "+failover.Backups[0])
+		return failover.Backups[0]
+	}
+	for _, backup := range failover.Backups {
+		err := runHealthCheck(failover.Check, backup)
+		if err == nil {
+			return backup
+		}
+		GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "Health check of backup IP "+backup+" failed. "+err.Error())
+	}
+	GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "No backup IP passed health check. Using "+failover.Backups[0])
+	return failover.Backups[0]
+}
+
+func isBackup(failover *Failover, ip string) bool {
+	for _, backup := range failover.Backups {
+		if backup == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// targetIP checks this server and returns the IP the record should point to.
+// Transitions between public IP and backup IP are notified.
+func (t *failoverTracker) targetIP(record DNSRecord, settings Settings, hooks *Hooks, pubIp string) string {
+	failover := record.Failover
+	if failover == nil {
+		return pubIp
+	}
+	if err := failover.validate(); err != nil {
+		GoDaddyDDNSLogger(ErrorLog, record.Name, record.Domain, "Invalid failover configuration, using public IP. "+err.Error())
+		return pubIp
+	}
+
+	failureThreshold := failover.FailureThreshold
+	if failureThreshold < 1 {
+		failureThreshold = default_failure_threshold
+	}
+	recoveryThreshold := failover.RecoveryThreshold
+	if recoveryThreshold < 1 {
+		recoveryThreshold = default_recovery_threshold
+	}
+
+	checkErr := runHealthCheck(failover.Check, pubIp)
+
+	t.mu.Lock()
+	state, ok := t.states[recordId(record)]
+	if !ok {
+		// Failover of previous run of the daemon is kept until this server recovers
+		state = &failoverHealth{}
+		if backup := failoverBackup(record); backup != "" && isBackup(failover, backup) {
+			state.active, state.backup = true, backup
+			GoDaddyDDNSLogger(InformationLog, record.Name, record.Domain, "Record is failed over to backup IP "+backup+" since previous run")
+		}
+		t.states[recordId(record)] = state
+	}
+
+	if checkErr != nil {
+		state.failures++
+		state.successes = 0
+		if state.active {
+			GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "Health check still failing. "+checkErr.Error())
+		} else {
+			GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, fmt.Sprintf("Health check failed (%d/%d). %s", state.failures, failureThreshold, checkErr.Error()))
+		}
+	} else {
+		state.successes++
+		state.failures = 0
+	}
+
+	switchToBackup := !state.active && state.failures >= failureThreshold
+	switchToPrimary := state.active && state.successes >= recoveryThreshold
+	t.mu.Unlock()
+
+	labels := metricLabels("domain", record.Domain, "name", record.Name)
+
+	switch {
+	case switchToBackup:
+		backup := backupIP(record, failover)
+		t.mu.Lock()
+		state.active, state.backup = true, backup
+		t.mu.Unlock()
+		saveFailoverBackup(record, backup)
+		daemonMetrics.set(metricFailoverActive, labels, 1)
+		notify(hooks, settings, record, WarningLog, "failover", fmt.Sprintf("Failing over to backup IP %s after %d failed health checks of %s", backup, failureThreshold, pubIp))
+		return backup
+	case switchToPrimary:
+		t.mu.Lock()
+		state.active, state.backup = false, ""
+		t.mu.Unlock()
+		saveFailoverBackup(record, "")
+		daemonMetrics.set(metricFailoverActive, labels, 0)
+		notify(hooks, settings, record, InformationLog, "failback", fmt.Sprintf("Switching back to %s after %d successful health checks", pubIp, recoveryThreshold))
+		return pubIp
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state.active {
+		daemonMetrics.set(metricFailoverActive, labels, 1)
+		return state.backup
+	}
+	daemonMetrics.set(metricFailoverActive, labels, 0)
+	return pubIp
+}
@@ -2,10 +2,22 @@ module github.com/navilg/godaddy-ddns
 
 go 1.17
 
-require github.com/jedib0t/go-pretty/v6 v6.3.0
+require (
+	filippo.io/age v1.0.0
+	github.com/BurntSushi/toml v1.2.1
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/jedib0t/go-pretty/v6 v6.3.0
+	github.com/miekg/dns v1.1.55
+	golang.org/x/crypto v0.9.0
+	golang.org/x/sys v0.8.0
+	golang.org/x/term v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/mattn/go-runewidth v0.0.13 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/sys v0.0.0-20180816055513-1c9583448a9c // indirect
+	golang.org/x/mod v0.8.0 // indirect
+	golang.org/x/net v0.10.0 // indirect
+	golang.org/x/tools v0.6.0 // indirect
 )
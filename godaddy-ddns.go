@@ -1,73 +1,55 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/navilg/godaddy-ddns/godaddy"
 )
 
-type CustomError struct {
-	ErrorCode int
-	Err       error
-}
-
-func (err *CustomError) Error() string {
-	return fmt.Sprintf("Error: %v, StatusCode: %d", err.Err, err.ErrorCode)
-}
-
 type DNSRecord struct {
-	Domain string `json:"domain"`
-	Name   string `json:"name"`
-	TTL    int    `json:"ttl"`
-	Key    string `json:"key"`
-	Secret string `json:"secret"`
+	Domain   string    `json:"domain"`
+	Name     string    `json:"name"`
+	TTL      int       `json:"ttl"`
+	Key      string    `json:"key,omitempty"`
+	Secret   string    `json:"secret,omitempty"`
+	Profile  string    `json:"profile,omitempty"`
+	Strategy string    `json:"strategy,omitempty"`
+	Failover *Failover `json:"failover,omitempty"`
+	Hooks    *Hooks    `json:"hooks,omitempty"`
+	Settings *Settings `json:"settings,omitempty"` // Overrides settings of configuration for the record
 }
 
 type Configuration struct {
-	Config []DNSRecord
-}
-
-type GodaddyRecordBody struct {
-	Data string `json:"data"`
-	Name string `json:"name"`
-	TTL  int    `json:"ttl"`
-	Type string `json:"type"`
-}
-
-type GodaddyErrorField struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Path    string `json:"path"`
-}
-
-type GodaddyErrorBody struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Fields  []GodaddyErrorField
+	Version     int                   `json:"version"`
+	Config      []DNSRecord           `json:"config"`
+	Settings    *Settings             `json:"settings,omitempty"`
+	Credentials map[string]Credential `json:"credentials,omitempty"`
+	Hooks       *Hooks                `json:"hooks,omitempty"`
+	Encryption  *Encryption           `json:"encryption,omitempty"`
 }
 
 var (
-	version             string        = "1.0.0-go1.17"
-	config_file         string        = "config.json"
-	home, _                           = os.UserHomeDir()
-	config_loc          string        = home + "/.config"
-	config_dir_perm     fs.FileMode   = 0700
-	config_file_perm    fs.FileMode   = 0600
-	godaddy_api_version string        = "v1"
-	max_record_size     int           = 5
-	daemon_poll_time    time.Duration = 1 * time.Minute // Time in minute
-	log_file            string        = config_loc + "/godaddy-ddns/log/godaddy-ddns.log"
+	version          string      = "1.0.0-go1.17"
+	config_file      string      = "config.json"
+	config_dir_perm  fs.FileMode = 0700
+	config_file_perm fs.FileMode = 0600
 )
 
 const (
@@ -76,12 +58,44 @@ const (
 	WarningLog     string = "WARN"
 )
 
+// Log file and level apply to the whole process. They are set once from settings at start.
+var logging = defaultSettings().Logging
+
+func configureLogging(settings *LoggingSettings) {
+	logging = settings
+}
+
+// logEnabled reports if messages of logType are written with configured level.
+func logEnabled(logType string) bool {
+	switch strings.ToLower(logging.Level) {
+	case "warn":
+		return logType != InformationLog
+	case "error":
+		return logType != InformationLog && logType != WarningLog
+	}
+	return true
+}
+
 func GoDaddyDDNSLogger(logType, name, domain, message string) {
-	file, err := os.OpenFile(log_file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
-		log.Fatal(err)
+	if !logEnabled(logType) {
+		return
+	}
+
+	// Log file is optional, e.g. with read-only home. Logs are always written to stdout.
+	var logWriter io.Writer = ioutil.Discard
+	if logging.File != "off" {
+		path, err := logFilePath()
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), config_dir_perm)
+		}
+		if err == nil {
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+			if err == nil {
+				defer file.Close()
+				logWriter = file
+			}
+		}
 	}
-	defer file.Close()
 
 	var (
 		WarningLogger       *log.Logger
@@ -92,9 +106,9 @@ func GoDaddyDDNSLogger(logType, name, domain, message string) {
 		StdoutErrorLogger   *log.Logger
 	)
 
-	InfoLogger = log.New(file, "INFO ", log.Ldate|log.Ltime)
-	WarningLogger = log.New(file, "WARN ", log.Ldate|log.Ltime)
-	ErrorLogger = log.New(file, "ERROR ", log.Ldate|log.Ltime)
+	InfoLogger = log.New(logWriter, "INFO ", log.Ldate|log.Ltime)
+	WarningLogger = log.New(logWriter, "WARN ", log.Ldate|log.Ltime)
+	ErrorLogger = log.New(logWriter, "ERROR ", log.Ldate|log.Ltime)
 	StdoutInfoLogger = log.New(os.Stdout, "INFO ", log.Ldate|log.Ltime)
 	StdoutWarningLogger = log.New(os.Stdout, "WARN ", log.Ldate|log.Ltime)
 	StdoutErrorLogger = log.New(os.Stdout, "ERROR ", log.Ldate|log.Ltime)
@@ -113,63 +127,6 @@ func GoDaddyDDNSLogger(logType, name, domain, message string) {
 	}
 }
 
-func init() {
-	if _, err := os.Stat(config_loc); os.IsNotExist(err) {
-		err := os.Mkdir(config_loc, config_dir_perm)
-		if err != nil {
-			fmt.Println("Failed to create directory,", config_loc, err.Error())
-			os.Exit(1)
-		}
-
-		err = os.Mkdir(config_loc+"/godaddy-ddns", config_dir_perm)
-		if err != nil {
-			fmt.Println("Failed to create directory,", config_loc+"/godaddy-ddns", err.Error())
-			os.Exit(1)
-		}
-
-		file, err := os.Create(config_loc + "/godaddy-ddns/" + config_file)
-		if err != nil {
-			fmt.Println("Failed to create config file,", config_loc+"/godaddy-ddns/"+config_file, err.Error())
-			os.Exit(1)
-		}
-		file.Close()
-	}
-
-	if _, err := os.Stat(config_loc + "/godaddy-ddns/"); os.IsNotExist(err) {
-		err = os.Mkdir(config_loc+"/godaddy-ddns", config_dir_perm)
-		if err != nil {
-			fmt.Println("Failed to create directory,", config_loc+"/godaddy-ddns", err.Error())
-			os.Exit(1)
-		}
-
-		file, err := os.Create(config_loc + "/godaddy-ddns/" + config_file)
-		if err != nil {
-			fmt.Println("Failed to create config file,", config_loc+"/godaddy-ddns/"+config_file, err.Error())
-			os.Exit(1)
-		}
-		file.Close()
-	}
-
-	if _, err := os.Stat(config_loc + "/godaddy-ddns/" + config_file); os.IsNotExist(err) {
-		file, err := os.Create(config_loc + "/godaddy-ddns/" + config_file)
-		if err != nil {
-			fmt.Println("Failed to create config file,", config_loc+"/godaddy-ddns/"+config_file, err.Error())
-			os.Exit(1)
-		}
-		file.Close()
-	}
-
-	if _, err := os.Stat(config_loc + "/godaddy-ddns/log"); os.IsNotExist(err) {
-		err := os.Mkdir(config_loc+"/godaddy-ddns/log", config_dir_perm)
-		if err != nil {
-			fmt.Println("Failed to create directory,", config_loc+"/godaddy-ddns/log", err.Error())
-			os.Exit(1)
-		}
-	}
-
-	// executablePath()
-}
-
 // func executablePath() {
 // 	ex, err := os.Executable()
 // 	if err != nil {
@@ -187,6 +144,12 @@ func main() {
 	ttl := addCmd.Int("ttl", 600, "Time-to-live in seconds. Minimum 600 seconds.")
 	key := addCmd.String("key", "", "Key value generated from godaddy developer console")
 	secret := addCmd.String("secret", "", "Secret value generated from godaddy developer console")
+	profile := addCmd.String("profile", "", "Credential profile to use instead of key and secret")
+	preHook := addCmd.String("pre-hook", "", "Command to run before the record is updated in GoDaddy")
+	postHook := addCmd.String("post-hook", "", "Command to run after the record is updated in GoDaddy")
+	hookTimeout := addCmd.Int("hook-timeout", 30, "Timeout for hook commands in seconds")
+	preHookVeto := addCmd.Bool("pre-hook-veto", false, "Skip the update if pre-hook exits with non-zero status")
+	strategy := addCmd.String("strategy", StrategyReplace, "'replace' to make public IP the only A record, 'merge' to add it next to IPs of other hosts")
 
 	deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
 	deleteDomain := deleteCmd.String("domain", "", "Domain name e.g. example.com")
@@ -198,12 +161,27 @@ func main() {
 	updateTtl := updateCmd.Int("ttl", 600, "Time-to-live in seconds. Minimum 600 seconds.")
 	updateKey := updateCmd.String("key", "", "Key value generated from godaddy developer console")
 	updateSecret := updateCmd.String("secret", "", "Secret value generated from godaddy developer console")
+	updateProfile := updateCmd.String("profile", "", "Credential profile to use instead of key and secret")
+	updatePreHook := updateCmd.String("pre-hook", "", "Command to run before the record is updated in GoDaddy")
+	updatePostHook := updateCmd.String("post-hook", "", "Command to run after the record is updated in GoDaddy")
+	updateHookTimeout := updateCmd.Int("hook-timeout", 30, "Timeout for hook commands in seconds")
+	updatePreHookVeto := updateCmd.Bool("pre-hook-veto", false, "Skip the update if pre-hook exits with non-zero status")
+	updateStrategy := updateCmd.String("strategy", "", "'replace' to make public IP the only A record, 'merge' to add it next to IPs of other hosts. Default is configured strategy")
+
+	daemonCmd := flag.NewFlagSet("daemon", flag.ExitOnError)
+	defaults := defaultSettings()
+	verifyPropagation := daemonCmd.Bool("verify-propagation", *defaults.Daemon.VerifyPropagation, "After update, wait until authoritative name servers of the domain serve the new IP")
+	propagationTimeout := daemonCmd.Duration("propagation-timeout", time.Duration(defaults.Daemon.PropagationTimeout), "Maximum time to wait for propagation")
+	lookup := daemonCmd.String("lookup", defaults.Daemon.Lookup, "How current state of records is read. 'api' or 'dns' (authoritative name servers, falls back to api)")
+	metricsListen := daemonCmd.String("metrics-listen", defaults.Daemon.MetricsListen, "Address to serve Prometheus metrics on e.g. :9153")
 
 	var usage = func() {
 		fmt.Printf("\nUsage:\n")
+		fmt.Printf("\tgodaddyddns [--config=file] command [options]\n")
+		fmt.Printf("\n\tConfiguration file defaults to GODADDYDDNS_CONFIG, $XDG_CONFIG_HOME/godaddy-ddns/config.json or ~/.config/godaddy-ddns/config.json\n")
 
 		fmt.Printf("\nadd\n")
-		fmt.Printf("\tAdd new record. Max 5 records\n")
+		fmt.Printf("\tAdd new record. Max 5 records unless daemon.max_records is set\n")
 		addCmd.PrintDefaults()
 		fmt.Printf("\nupdate\n")
 		fmt.Printf("\tUpdate existing record\n")
@@ -213,24 +191,51 @@ func main() {
 		deleteCmd.PrintDefaults()
 		fmt.Printf("\nlist\n")
 		fmt.Printf("\tList all configured records\n")
+		fmt.Printf("\ndaemon\n")
+		fmt.Printf("\tKeep configured records updated with public IP\n")
+		daemonCmd.PrintDefaults()
+		fmt.Printf("\nverify\n")
+		fmt.Printf("\tCheck credentials, domain status and name servers of configured records. Use --domain and --name to check some records\n")
+		fmt.Printf("\nrecord get|add|replace|delete\n")
+		fmt.Printf("\tManage A, AAAA, CNAME, TXT, MX, SRV, CAA and NS records of the domain. Run 'godaddyddns record' for options\n")
+		fmt.Printf("\nzone export|import|plan|apply\n")
+		fmt.Printf("\tExport, import or declaratively manage all records of the domain. Run 'godaddyddns zone' for options\n")
+		fmt.Printf("\nacme present|cleanup\n")
+		fmt.Printf("\tAdd or remove ACME DNS-01 challenge for certbot and lego. Run 'godaddyddns acme' for options\n")
+		fmt.Printf("\ncredentials add|rotate|list|remove\n")
+		fmt.Printf("\tManage credential profiles shared by records. Run 'godaddyddns credentials' for options\n")
+		fmt.Printf("\nconfig encrypt|decrypt|validate|schema\n")
+		fmt.Printf("\tEncrypt, decrypt or validate configuration. Run 'godaddyddns config' for options\n")
 		fmt.Printf("\nversion\n")
 		fmt.Printf("\tCheck version\n")
 		fmt.Printf("\n\nExamples\n")
 		fmt.Printf("\tgodaddyddns list\n")
 		fmt.Printf("\tgodaddyddns add --domain='example.com' --name='myweb' --ttl=1200 --key='kEyGeneratedFr0mG0DaddY' --secret='s3cRe7GeneratedFr0mG0DaddY'\n")
 		fmt.Printf("\tgodaddyddns update --domain='example.com' --name='myweb' --key='kEyGeneratedFr0mG0DaddY' --secret='s3cRe7GeneratedFr0mG0DaddY'\n")
+		fmt.Printf("\tgodaddyddns update --domain='example.com' --name='myweb' --key='kEyGeneratedFr0mG0DaddY' --secret='s3cRe7GeneratedFr0mG0DaddY' --post-hook='systemctl restart wg-quick@wg0'\n")
 		fmt.Printf("\tgodaddyddns delete --domain='example.com' --name='myweb'\n")
+		fmt.Printf("\tgodaddyddns record add --domain='example.com' --type=TXT --name='@' --data='v=spf1 -all'\n")
 		fmt.Printf("\tgodaddyddns version'\n")
 		fmt.Printf("\nTo uninstall (If installed using convenient script)\n")
 		fmt.Printf("\tsudo godaddyddns-uninstall.sh\n")
 	}
 
+	// --config is the only flag accepted before the command
+	os.Args = append(os.Args[:1], parseGlobalFlags(os.Args[1:])...)
+
 	if len(os.Args) < 2 {
 		fmt.Println("Atleast one argument required")
 		usage()
-		os.Exit(1)
+		os.Exit(ExitUsage)
 	}
 
+	// Settings are read once and passed to the commands
+	settings := defaults
+	if usesConfiguration(os.Args[1:]) {
+		settings = defaults.overlay(loadSettings())
+	}
+	configureLogging(settings.Logging)
+
 	switch os.Args[1] {
 
 	case "version":
@@ -239,23 +244,29 @@ func main() {
 
 	case "add":
 		addCmd.Parse(os.Args[2:])
-		if *domain == "" || *name == "" || *key == "" || *secret == "" {
-			fmt.Println("ERROR domain, name, key and secret are mandatory")
+		if *domain == "" || *name == "" || ((*key == "" || *secret == "") && *profile == "") {
+			fmt.Println("ERROR domain, name and either key and secret or profile are mandatory")
 			fmt.Printf("\nUsage of %s:\n", os.Args[1])
 			addCmd.PrintDefaults()
-			os.Exit(1)
+			os.Exit(ExitUsage)
 		}
 		if *ttl < 600 {
 			fmt.Println("ERROR TTL value cannot be less than 600 seconds.")
 			fmt.Printf("\nUsage of %s:\n", os.Args[1])
 			addCmd.PrintDefaults()
-			os.Exit(1)
+			os.Exit(ExitUsage)
+		}
+
+		if *strategy != StrategyReplace && *strategy != StrategyMerge {
+			fmt.Println("ERROR strategy must be one of replace, merge")
+			os.Exit(ExitUsage)
 		}
 
-		err := addRecord(*domain, *name, *key, *secret, *ttl, false)
+		hooks := hooksFromFlags(*preHook, *postHook, *hookTimeout, *preHookVeto)
+		err := addRecord(settings, *domain, *name, *key, *secret, *profile, *ttl, *strategy, hooks, false)
 		if err != nil {
 			GoDaddyDDNSLogger(ErrorLog, *name, *domain, err.Error()+" Failed to add record.")
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 	case "delete":
@@ -264,47 +275,90 @@ func main() {
 			fmt.Println("ERROR domain and name are mandatory")
 			fmt.Printf("\nUsage of %s:\n", os.Args[1])
 			deleteCmd.PrintDefaults()
-			os.Exit(1)
+			os.Exit(ExitUsage)
 		}
-		err := deleteRecord(*deleteDomain, *deleteName)
+		err := deleteRecord(settings, *deleteDomain, *deleteName)
 		if err != nil {
 			GoDaddyDDNSLogger(ErrorLog, *deleteName, *deleteDomain, "Failed to delete record. "+err.Error())
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		} else {
 			GoDaddyDDNSLogger(InformationLog, *deleteName, *deleteDomain, "Record removed from configuration. If not in use, delete the record manually from GoDaddy console.")
 		}
 
 	case "update":
 		updateCmd.Parse(os.Args[2:])
-		if *updateDomain == "" || *updateName == "" || *updateKey == "" || *updateSecret == "" {
-			fmt.Println("ERROR domain, name, key and secret are mandatory")
+		if *updateDomain == "" || *updateName == "" || ((*updateKey == "" || *updateSecret == "") && *updateProfile == "") {
+			fmt.Println("ERROR domain, name and either key and secret or profile are mandatory")
 			fmt.Printf("\nUsage of %s:\n", os.Args[1])
 			updateCmd.PrintDefaults()
-			os.Exit(1)
+			os.Exit(ExitUsage)
 		}
 		if *ttl < 600 {
 			fmt.Println("ERROR TTL value cannot be less than 600 seconds.")
 			fmt.Printf("\nUsage of %s:\n", os.Args[1])
 			updateCmd.PrintDefaults()
-			os.Exit(1)
+			os.Exit(ExitUsage)
+		}
+		if *updateStrategy != "" && *updateStrategy != StrategyReplace && *updateStrategy != StrategyMerge {
+			fmt.Println("ERROR strategy must be one of replace, merge")
+			os.Exit(ExitUsage)
 		}
-		err := addRecord(*updateDomain, *updateName, *updateKey, *updateSecret, *updateTtl, true)
+		hooks := hooksFromFlags(*updatePreHook, *updatePostHook, *updateHookTimeout, *updatePreHookVeto)
+		err := addRecord(settings, *updateDomain, *updateName, *updateKey, *updateSecret, *updateProfile, *updateTtl, *updateStrategy, hooks, true)
 		if err != nil {
 			GoDaddyDDNSLogger(ErrorLog, *updateName, *updateDomain, "Failed to update record. "+err.Error())
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 	case "daemon":
 		// ticker := time.NewTicker(daemon_poll_time * time.Minute)
 		// quit := make(chan struct{})
 		// go daemonDDNS(ticker, &quit)
-		daemonDDNS()
+		daemonCmd.Parse(os.Args[2:])
+		if *lookup != "api" && *lookup != "dns" {
+			fmt.Println("ERROR lookup must be one of api, dns")
+			os.Exit(ExitUsage)
+		}
+
+		// Flags given on command line take precedence over settings of configuration and records
+		flags := &Settings{Daemon: &DaemonSettings{}}
+		daemonCmd.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "verify-propagation":
+				flags.Daemon.VerifyPropagation = verifyPropagation
+			case "propagation-timeout":
+				flags.Daemon.PropagationTimeout = Duration(*propagationTimeout)
+			case "lookup":
+				flags.Daemon.Lookup = *lookup
+			case "metrics-listen":
+				flags.Daemon.MetricsListen = *metricsListen
+			}
+		})
+		daemonDDNS(settings, flags)
+
+	case "record":
+		recordCmd(os.Args[2:], settings)
+
+	case "zone":
+		zoneCmd(os.Args[2:], settings)
+
+	case "acme":
+		acmeCmd(os.Args[2:], settings)
+
+	case "credentials":
+		credentialsCmd(os.Args[2:], settings)
+
+	case "config":
+		configCmd(os.Args[2:], settings)
+
+	case "verify":
+		verifyCmd(os.Args[2:], settings)
 
 	case "list":
 		err := listRecord()
 		if err != nil {
 			fmt.Println("Failed to list records,", err.Error())
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 
 	default:
@@ -313,102 +367,126 @@ func main() {
 
 }
 
-func addRecord(domain, name, key, secret string, ttl int, isUpdate bool) error {
-	record := DNSRecord{
-		Domain: domain,
-		Name:   name,
-		Key:    key,
-		Secret: secret,
-		TTL:    ttl,
+// usesConfiguration reports if command of args reads configuration file.
+func usesConfiguration(args []string) bool {
+	switch args[0] {
+	case "version":
+		return false
+	case "config":
+		return len(args) < 2 || args[1] != "schema"
 	}
+	return true
+}
 
-	var config Configuration
-	var updatedConfig Configuration
-	var hasUpdated bool = false
+func hooksFromFlags(preHook, postHook string, timeout int, veto bool) *Hooks {
+	if preHook == "" && postHook == "" {
+		return nil
+	}
 
-	body, err := getDNSRecord(name, domain, key, secret)
+	hooks := &Hooks{}
+	if preHook != "" {
+		hooks.PreUpdate = &Hook{Command: preHook, Timeout: timeout, Veto: veto}
+	}
+	if postHook != "" {
+		hooks.PostUpdate = &Hook{Command: postHook, Timeout: timeout}
+	}
+	return hooks
+}
+
+// addRecord adds or updates the record in configuration and GoDaddy. Empty strategy
+// keeps the configured strategy on update. GoDaddy and hooks are called without holding
+// the lock of configuration, which is then read again to add the record.
+func addRecord(settings Settings, domain, name, key, secret, profile string, ttl int, strategy string, hooks *Hooks, isUpdate bool) error {
+	record := DNSRecord{
+		Domain:   domain,
+		Name:     name,
+		Key:      key,
+		Secret:   secret,
+		Profile:  profile,
+		TTL:      ttl,
+		Strategy: strategy,
+		Hooks:    hooks,
+	}
+
+	// Key and secret of profile are not copied to the record. Secret references
+	// are kept as they are in configuration.
+	if profile != "" {
+		record.Key, record.Secret = "", ""
+	}
+	config, err := loadConfiguration()
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("addRecord Error getting DNS record " + err.Error())}
-		// return err
+		return err
+	}
+	// Existing or missing record is reported before GoDaddy is called
+	_, record, err = mergeRecord(config, record, isUpdate, settings.Daemon.MaxRecords)
+	if err != nil {
+		return err
 	}
+	recordSettings := settings.overlay(record.Settings)
 
-	var recordsBody []GodaddyRecordBody
-	err = json.Unmarshal([]byte(body), &recordsBody)
+	resolved, err := resolveCredentials(config, record)
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("addRecord Error Unmarshalling DNS record " + err.Error())}
-		// return err
+		return err
 	}
 
-	var existingTtl int
-	var existingIp string
+	client := newGodaddyClient(recordSettings.HTTP, name, domain, resolved.Key, resolved.Secret)
 
-	if len(recordsBody) != 0 {
-		existingTtl = recordsBody[0].TTL
-		existingIp = recordsBody[0].Data
-	} else {
-		existingTtl = 0
-		existingIp = ""
+	// Bad key or inactive domain is reported before the record is configured. Zones
+	// hosted in GoDaddy DNS of domains registered elsewhere are not found in domains
+	// API, and external name servers may be in the middle of a migration, so both are
+	// only warned about here. verify command fails on them.
+	err = checkDomain(client, domain)
+	var notFoundErr *godaddy.NotFoundError
+	if errors.As(err, &notFoundErr) || errors.Is(err, ErrExternalNameservers) {
+		GoDaddyDDNSLogger(WarningLog, name, domain, verifyHint(err)+" "+err.Error())
+	} else if err != nil {
+		return fmt.Errorf("addRecord %s %w", verifyHint(err), err)
 	}
 
-	pubIp, err := getPubIP()
+	current, err := client.GetRecords(context.Background(), domain, godaddy.TypeA, name)
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("addRecord Error getting public IP of server " + err.Error())}
+		return fmt.Errorf("addRecord Error getting DNS record %w", err)
 		// return err
 	}
 
-	configFileContent, err := ioutil.ReadFile(config_loc + "/godaddy-ddns/" + config_file)
+	pubIp, err := getPubIP(recordSettings.IPDetection)
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("addRecord Error reading configuration " + err.Error())}
+		return fmt.Errorf("addRecord Error getting public IP of server %w", err)
 		// return err
 	}
 
-	if len(configFileContent) != 0 {
-		err = json.Unmarshal(configFileContent, &config)
+	resolved.Strategy, resolved.Hooks, resolved.Failover, resolved.Settings = record.Strategy, record.Hooks, record.Failover, record.Settings
+	desired, existingIp, existingTtl, changed := planRecord(resolved, current, pubIp, lastIP(resolved))
+	if changed {
+		err := updateRecordWithHooks(resolved, recordSettings, mergeHooks(config.Hooks, record.Hooks), existingIp, existingTtl, pubIp, desired)
+		if errors.Is(err, ErrUpdateVetoed) {
+			return fmt.Errorf("addRecord %w", err)
+		}
 		if err != nil {
-			return &CustomError{ErrorCode: 1, Err: errors.New("addRecord Error unmarshalling configuration " + err.Error())}
+			return fmt.Errorf("addRecord Error setting DNS record %w", err)
 			// return err
 		}
-		if len(config.Config) >= max_record_size && !isUpdate {
-			return &CustomError{ErrorCode: 1, Err: errors.New("reached record limit. maximum " + fmt.Sprintf("%v", max_record_size) + " records allowed per server")}
-		}
-		for _, i := range config.Config {
-			if i.Domain == domain && i.Name == name {
-				if !isUpdate {
-					return &CustomError{ErrorCode: 1, Err: errors.New("record already exist")}
-				} else {
-					hasUpdated = true
-					continue
-				}
-			}
-			updatedConfig.Config = append(updatedConfig.Config, i)
-		}
-		if isUpdate && !hasUpdated {
-			return &CustomError{ErrorCode: 1, Err: errors.New("record not found")}
-		}
-		updatedConfig.Config = append(updatedConfig.Config, record)
-	} else {
-		updatedConfig.Config = append(updatedConfig.Config, record)
 	}
 
-	configFileContent, err = json.MarshalIndent(updatedConfig, "", "  ")
+	unlock, err := lockConfigFile(settings.ConfigFile)
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("addRecord Error marshalling configuration " + err.Error())}
-		// return err
+		return &ConfigError{Op: "addRecord Error locking", Err: err}
 	}
+	defer unlock()
 
-	if ttl != existingTtl || pubIp != existingIp {
-		_, err := setDNSRecord(name, domain, key, secret, pubIp, ttl)
-		if err != nil {
-			return &CustomError{ErrorCode: 1, Err: errors.New("addRecord Error setting DNS record " + err.Error())}
-			// return err
-		}
+	// Configuration may have been changed by another command in the meantime
+	config, err = loadConfiguration()
+	if err != nil {
+		return err
+	}
+	config, _, err = mergeRecord(config, record, isUpdate, settings.Daemon.MaxRecords)
+	if err != nil {
+		return err
 	}
 
-	err = ioutil.WriteFile(config_loc+"/godaddy-ddns/"+config_file, configFileContent, config_file_perm)
-
+	err = saveConfiguration(config, settings.ConfigFile)
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("addRecord Error writing to configuration " + err.Error())}
-		// return err
+		return err
 	}
 
 	GoDaddyDDNSLogger(InformationLog, name, domain, "Record created/updated (ttl: "+fmt.Sprintf("%d", ttl)+", ip: "+pubIp+", key: ****, secret: ****)")
@@ -416,167 +494,130 @@ func addRecord(domain, name, key, secret string, ttl int, isUpdate bool) error {
 	return nil
 }
 
-func getDNSRecord(name, domain, key, secret string) (string, error) {
-	// Get record details from GoDaddy
-
-	gdURL := "https://api.godaddy.com/" + godaddy_api_version + "/domains/" + domain + "/records/A/" + name
-	authorization := key + ":" + secret
-
-	apiclient := &http.Client{}
-
-	req, err := http.NewRequest("GET", gdURL, nil)
-	if err != nil {
-		// fmt.Println(err.Error())
-		return "", err
+// mergeRecord returns config with record added, or replacing the configured record of same
+// domain and name on update, and the record as configured. On update, hooks and strategy
+// which are not passed, failover and settings are kept from the configured record.
+func mergeRecord(config Configuration, record DNSRecord, isUpdate bool, maxRecords int) (Configuration, DNSRecord, error) {
+	if len(config.Config) >= maxRecords && !isUpdate {
+		return config, record, fmt.Errorf("%w. maximum %v records allowed per server", ErrRecordLimit, maxRecords)
+	}
+
+	// Everything but records is kept as it is
+	updatedConfig := config
+	updatedConfig.Config = nil
+	hasUpdated := false
+	for _, i := range config.Config {
+		if i.Domain == record.Domain && i.Name == record.Name {
+			if !isUpdate {
+				return config, record, ErrRecordExists
+			}
+			hasUpdated = true
+			if record.Hooks == nil {
+				record.Hooks = i.Hooks // Keep configured hooks if not passed during update
+			}
+			if record.Strategy == "" {
+				record.Strategy = i.Strategy
+			}
+			record.Failover = i.Failover
+			record.Settings = i.Settings
+			continue
+		}
+		updatedConfig.Config = append(updatedConfig.Config, i)
 	}
-
-	req.Header.Add("Authorization", "sso-key "+authorization)
-	response, err := apiclient.Do(req)
-	if err != nil {
-		// fmt.Println(err.Error())
-		return "", err
+	if isUpdate && !hasUpdated {
+		return config, record, ErrRecordNotFound
 	}
 
-	defer response.Body.Close()
-
-	// var bodyBytes []byte
-
-	bodyBytes, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		// fmt.Println(err.Error())
-		return "", err
+	// Replace is the default strategy and is not written to configuration
+	configured := record
+	if configured.Strategy == StrategyReplace {
+		configured.Strategy = ""
 	}
+	updatedConfig.Config = append(updatedConfig.Config, configured)
+	return updatedConfig, record, nil
+}
 
-	if response.StatusCode != 200 {
-		var errorBody GodaddyErrorBody
-		err := json.Unmarshal(bodyBytes, &errorBody)
-		if err != nil {
-			// fmt.Println(err.Error())
-			return "", err
+// getPubIP returns public IP of the server from the first source which answers.
+func getPubIP(settings *IPDetectionSettings) (string, error) {
+	client := &http.Client{Timeout: time.Duration(settings.Timeout)}
+
+	err := errors.New("no IP detection source configured")
+	for _, source := range settings.Sources {
+		var ip string
+		ip, err = lookupPubIP(client, source)
+		if err == nil {
+			return ip, nil
 		}
-		return "", &CustomError{ErrorCode: response.StatusCode, Err: errors.New(errorBody.Message)}
 	}
 
-	return string(bodyBytes), nil
+	return "", &IPDetectionError{Err: err}
 }
 
-// func addGodaddyRecord() {
-
-// }
-func getPubIP() (string, error) {
+// lookupPubIP reads public IP from source. Source returns json with ip field or the IP as plain text.
+func lookupPubIP(client *http.Client, source string) (string, error) {
 
 	type GetIPBody struct {
 		IP string `json:"ip"`
 	}
 
 	var ipbody GetIPBody
-	var response *http.Response
-
-	response, err := http.Get("https://api.ipify.org/?format=json")
-	if err != nil {
-		response, err = http.Get("https://ipinfo.io/json")
-		if err != nil {
-			return "", nil
-		}
-	}
 
-	defer response.Body.Close()
-	bodyBytes, err := ioutil.ReadAll(response.Body)
+	response, err := client.Get(source)
 	if err != nil {
-		// fmt.Println(err.Error())
 		return "", err
 	}
 
-	err = json.Unmarshal(bodyBytes, &ipbody)
-	if err != nil {
-		// fmt.Println(err.Error())
-		return "", err
-	}
-
-	return ipbody.IP, nil
-
-}
-
-func setDNSRecord(name, domain, key, secret, pubIp string, ttl int) (string, error) {
-
-	gdURL := "https://api.godaddy.com/" + godaddy_api_version + "/domains/" + domain + "/records/A/" + name
-	authorization := key + ":" + secret
+	defer response.Body.Close()
 
-	type Data struct {
-		Data string `json:"data"`
-		TTL  int    `json:"ttl"`
+	if response.StatusCode != 200 {
+		return "", fmt.Errorf("unexpected status %d from %s", response.StatusCode, response.Request.URL.Host)
 	}
 
-	data, _ := json.Marshal([]Data{
-		{
-			Data: pubIp,
-			TTL:  ttl,
-		},
-	})
-
-	requestBody := bytes.NewBuffer(data)
-	// requestBody := bytes.NewBuffer()
-
-	apiclient := &http.Client{}
-
-	req, err := http.NewRequest("PUT", gdURL, requestBody)
+	bodyBytes, err := ioutil.ReadAll(io.LimitReader(response.Body, 64*1024))
 	if err != nil {
-		// fmt.Println(1, err.Error())
 		return "", err
 	}
 
-	req.Header.Add("Authorization", "sso-key "+authorization)
-	req.Header.Add("Content-Type", "application/json")
+	if json.Unmarshal(bodyBytes, &ipbody) != nil {
+		ipbody.IP = strings.TrimSpace(string(bodyBytes))
+	}
 
-	response, err := apiclient.Do(req)
-	if err != nil {
-		// fmt.Println(2, err.Error())
-		return "", err
+	if net.ParseIP(ipbody.IP) == nil {
+		return "", errors.New("invalid IP address " + ipbody.IP + " from " + response.Request.URL.Host)
 	}
 
-	defer response.Body.Close()
+	return ipbody.IP, nil
 
-	var bodyBytes []byte
+}
 
-	if response.StatusCode != 200 {
-		var errorBody GodaddyErrorBody
-		bodyBytes, err = ioutil.ReadAll(response.Body)
-		if err != nil {
-			// fmt.Println(err.Error())
-			return "", err
-		}
-		err := json.Unmarshal(bodyBytes, &errorBody)
-		if err != nil {
-			// fmt.Println(3, err.Error())
-			return "", err
-		}
-		// return "", &CustomError{ErrorCode: response.StatusCode, Err: errors.New(errorBody.Message)}
+func deleteRecord(settings Settings, domain, name string) error {
 
-		return "", &CustomError{ErrorCode: response.StatusCode, Err: errors.New(errorBody.Message)}
+	unlock, err := lockConfigFile(settings.ConfigFile)
+	if err != nil {
+		return &ConfigError{Op: "deleteRecord Error locking", Err: err}
 	}
-
-	return string(bodyBytes), nil
-}
-
-func deleteRecord(domain, name string) error {
+	defer unlock()
 
 	var config Configuration
 	var newConfig Configuration
 	var done bool = false
 
-	configFileContent, err := ioutil.ReadFile(config_loc + "/godaddy-ddns/" + config_file)
+	configFileContent, err := readConfigFile()
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("deleteRecord Error reading configuration " + err.Error())}
+		return &ConfigError{Op: "deleteRecord Error reading", Err: err}
 		// return err
 	}
 
 	if len(configFileContent) != 0 {
-		err = json.Unmarshal(configFileContent, &config)
+		config, err = decodeConfiguration(configFileContent)
 		if err != nil {
-			return &CustomError{ErrorCode: 1, Err: errors.New("deleteRecord Error unmarshalling configuration " + err.Error())}
+			return &ConfigError{Op: "deleteRecord Error unmarshalling", Err: err}
 			// return err
 		}
 
+		// Everything but records is kept as it is
+		newConfig = config
+		newConfig.Config = nil
 		for _, i := range config.Config {
 			if i.Domain == domain && i.Name == name {
 				done = true
@@ -587,19 +628,18 @@ func deleteRecord(domain, name string) error {
 	}
 
 	if len(configFileContent) == 0 || !done {
-		return &CustomError{ErrorCode: 1, Err: errors.New("record doesnot exist")}
+		return ErrRecordNotFound
 	}
 
-	configFileContent, err = json.MarshalIndent(newConfig, "", "  ")
+	configFileContent, err = encodeConfiguration(newConfig)
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("deleteRecord Error marshalling configuration " + err.Error())}
-		// return err
+		return err
 	}
 
-	err = ioutil.WriteFile(config_loc+"/godaddy-ddns/"+config_file, configFileContent, config_file_perm)
+	err = writeConfigFile(configFileContent, settings.ConfigFile)
 
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("deleteRecord Error writing configuration " + err.Error())}
+		return &ConfigError{Op: "deleteRecord Error writing", Err: err}
 		// return err
 	}
 
@@ -609,49 +649,71 @@ func deleteRecord(domain, name string) error {
 func listRecord() error {
 	var config Configuration
 
-	configFileContent, err := ioutil.ReadFile(config_loc + "/godaddy-ddns/" + config_file)
+	configFileContent, err := readConfigFile()
 	if err != nil {
-		return &CustomError{ErrorCode: 1, Err: errors.New("listRecord Error reading configuration " + err.Error())}
+		return &ConfigError{Op: "listRecord Error reading", Err: err}
 		// return err
 	}
 
 	if len(configFileContent) != 0 {
-		err = json.Unmarshal(configFileContent, &config)
+		config, err = decodeConfiguration(configFileContent)
 		if err != nil {
-			return &CustomError{ErrorCode: 1, Err: errors.New("listRecord Error unmarshalling configuration " + err.Error())}
+			return &ConfigError{Op: "listRecord Error unmarshalling", Err: err}
 			// return err
 		}
 
 		if len(config.Config) == 0 {
-			return &CustomError{ErrorCode: 1, Err: errors.New("no record exist")}
+			return ErrNoRecords
 		}
 
 		t := table.NewWriter()
 
 		t.SetOutputMirror(os.Stdout)
-		t.AppendHeader(table.Row{"#", "Name", "Domain", "TTL"})
+		t.AppendHeader(table.Row{"#", "Name", "Domain", "TTL", "Profile"})
 
 		for i, rec := range config.Config {
-			t.AppendRow(table.Row{i + 1, rec.Name, rec.Domain, rec.TTL})
+			t.AppendRow(table.Row{i + 1, rec.Name, rec.Domain, rec.TTL, rec.Profile})
 		}
 		t.Render()
 
 	} else {
-		return &CustomError{ErrorCode: 1, Err: errors.New("no record exist")}
+		return ErrNoRecords
 	}
 
 	return nil
 }
 
-func daemonDDNS() {
+// daemonDDNS polls the records. Settings are read again on every poll, except those
+// applying to the whole process, which are kept from start. flags take precedence.
+func daemonDDNS(settings Settings, flags *Settings) {
 
 	GoDaddyDDNSLogger(InformationLog, "", "", "Starting daemon process")
 
-	ticker := time.NewTicker(daemon_poll_time)
+	if version, err := storedConfigVersion(); err == nil && version < currentConfigVersion {
+		GoDaddyDDNSLogger(WarningLog, "", "", fmt.Sprintf("Configuration is version %d. Run config migrate to upgrade it to version %d", version, currentConfigVersion))
+	}
+
+	daemon := settings.overlay(flags).Daemon
+	if daemon.PollInterval <= 0 {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Invalid daemon.poll_interval "+time.Duration(daemon.PollInterval).String())
+		os.Exit(ExitConfig)
+	}
+	if daemon.MetricsListen != "" {
+		go serveMetrics(daemon.MetricsListen)
+	}
+
+	ticker := time.NewTicker(time.Duration(daemon.PollInterval))
 	done := make(chan bool)
+	breaker := newRecordBreaker(time.Duration(daemon.PollInterval), time.Duration(daemon.MaxBackoff))
+	failovers := newFailoverTracker()
 
-	if _, err := os.Stat(config_loc + "/godaddy-ddns/" + "daemon.lock"); !os.IsNotExist(err) {
-		err = os.Remove(config_loc + "/godaddy-ddns/" + "daemon.lock")
+	lockFile, err := lockFilePath()
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to find state directory. "+err.Error())
+		os.Exit(ExitConfig)
+	}
+	if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+		err = os.Remove(lockFile)
 		if err != nil {
 			GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to release lock")
 			os.Exit(1)
@@ -665,100 +727,7 @@ func daemonDDNS() {
 				return
 
 			case <-ticker.C:
-
-				GoDaddyDDNSLogger(InformationLog, "", "", "Polling the records")
-
-				var config Configuration
-
-				if _, err := os.Stat(config_loc + "/godaddy-ddns/" + "daemon.lock"); !os.IsNotExist(err) {
-					GoDaddyDDNSLogger(WarningLog, "", "", "A daemon is already running. Waiting to release lock")
-					continue
-				}
-
-				file, err := os.Create(config_loc + "/godaddy-ddns/" + "daemon.lock")
-				if err != nil {
-					GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to apply lock")
-					continue
-				}
-				file.Close()
-
-				configFileContent, err := ioutil.ReadFile(config_loc + "/godaddy-ddns/" + config_file)
-				if err != nil {
-					GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to read configuration file. "+err.Error())
-					continue
-				}
-
-				if len(configFileContent) != 0 {
-					err = json.Unmarshal(configFileContent, &config)
-					if err != nil {
-						GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to read configuration file. "+err.Error())
-						continue
-					}
-
-					if len(config.Config) == 0 {
-						GoDaddyDDNSLogger(WarningLog, "", "", "No record found in configuration")
-						continue
-					}
-
-					for _, i := range config.Config {
-
-						name := i.Name
-						domain := i.Domain
-						key := i.Key
-						secret := i.Secret
-						ttl := i.TTL
-
-						body, err := getDNSRecord(name, domain, key, secret)
-						if err != nil {
-							GoDaddyDDNSLogger(ErrorLog, name, domain, "Failed to get current state of record. "+err.Error())
-							continue
-						}
-
-						var recordsBody []GodaddyRecordBody
-						err = json.Unmarshal([]byte(body), &recordsBody)
-						if err != nil {
-							GoDaddyDDNSLogger(ErrorLog, name, domain, "Failed to read current state of record. "+err.Error())
-							continue
-						}
-
-						var existingTtl int
-						var existingIp string
-
-						if len(recordsBody) != 0 {
-							existingTtl = recordsBody[0].TTL
-							existingIp = recordsBody[0].Data
-						} else {
-							existingTtl = 0
-							existingIp = ""
-						}
-
-						pubIp, err := getPubIP()
-						if err != nil {
-							GoDaddyDDNSLogger(ErrorLog, name, domain, "Failed to get current Pub IP of server. "+err.Error())
-							continue
-						}
-
-						if ttl != existingTtl || pubIp != existingIp {
-							_, err := setDNSRecord(name, domain, key, secret, pubIp, ttl)
-							if err != nil {
-								GoDaddyDDNSLogger(ErrorLog, name, domain, "Failed to update record. "+err.Error())
-								continue
-							} else {
-								GoDaddyDDNSLogger(InformationLog, name, domain, "Record updated (ttl: "+fmt.Sprintf("%d", existingTtl)+"->"+fmt.Sprintf("%d", ttl)+", ip: "+existingIp+"->"+pubIp+")")
-							}
-						} else {
-							GoDaddyDDNSLogger(InformationLog, name, domain, "Desired state is current state")
-						}
-
-						time.Sleep(10 * time.Second) // Wait for 10 seconds before picking next record.
-					}
-				}
-
-				err = os.Remove(config_loc + "/godaddy-ddns/" + "daemon.lock")
-				if err != nil {
-					GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to release lock")
-					continue
-				}
+				pollRecords(settings, flags, breaker, failovers, lockFile)
 			}
 		}
 	}()
@@ -772,8 +741,8 @@ func daemonDDNS() {
 			GoDaddyDDNSLogger(InformationLog, "", "", "Interrupt signal received. Exiting")
 			ticker.Stop()
 			done <- true
-			if _, err := os.Stat(config_loc + "/godaddy-ddns/" + "daemon.lock"); !os.IsNotExist(err) {
-				_ = os.Remove(config_loc + "/godaddy-ddns/" + "daemon.lock")
+			if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+				_ = os.Remove(lockFile)
 			}
 			os.Exit(0)
 		}
@@ -783,3 +752,228 @@ func daemonDDNS() {
 	ticker.Stop()
 	done <- true
 }
+
+// pollRecords reconciles all configured records once. Records are processed concurrently
+// by daemon.workers workers sharing the GoDaddy API rate limiter.
+func pollRecords(settings Settings, flags *Settings, breaker *recordBreaker, failovers *failoverTracker, lockFile string) {
+
+	GoDaddyDDNSLogger(InformationLog, "", "", "Polling the records")
+
+	var config Configuration
+
+	if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+		GoDaddyDDNSLogger(WarningLog, "", "", "A daemon is already running. Waiting to release lock")
+		return
+	}
+
+	err := os.MkdirAll(filepath.Dir(lockFile), config_dir_perm)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to create state directory. "+err.Error())
+		return
+	}
+
+	file, err := os.Create(lockFile)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to apply lock")
+		return
+	}
+	file.Close()
+
+	defer func() {
+		err := os.Remove(lockFile)
+		if err != nil {
+			GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to release lock")
+		}
+	}()
+
+	configFileContent, err := readConfigFile()
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to read configuration file. "+err.Error())
+		return
+	}
+
+	if len(configFileContent) == 0 {
+		return
+	}
+
+	config, err = decodeConfiguration(configFileContent)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to read configuration file. "+err.Error())
+		return
+	}
+
+	if len(config.Config) == 0 {
+		GoDaddyDDNSLogger(WarningLog, "", "", "No record found in configuration")
+		return
+	}
+
+	settings = reloadSettings(settings, config.Settings)
+
+	type job struct {
+		record   DNSRecord
+		settings Settings
+		pubIp    string
+	}
+
+	var jobs []job
+	// Public IP is same for records with same IP detection sources. Lookup once per poll.
+	pubIps := make(map[string]string)
+
+	for _, i := range config.Config {
+		i, err := resolveCredentials(config, i)
+		if err != nil {
+			GoDaddyDDNSLogger(ErrorLog, i.Name, i.Domain, err.Error())
+			continue
+		}
+		if !breaker.allow(i, time.Now()) {
+			continue
+		}
+
+		recordSettings := settings.overlay(i.Settings, flags)
+		sources := strings.Join(recordSettings.IPDetection.Sources, " ")
+		pubIp, ok := pubIps[sources]
+		if !ok {
+			pubIp, err = getPubIP(recordSettings.IPDetection)
+			if err != nil {
+				GoDaddyDDNSLogger(ErrorLog, i.Name, i.Domain, "Failed to get current Pub IP of server. "+err.Error())
+				continue
+			}
+			pubIps[sources] = pubIp
+		}
+
+		jobs = append(jobs, job{record: i, settings: recordSettings, pubIp: pubIp})
+	}
+
+	if len(jobs) == 0 {
+		return
+	}
+
+	queue := make(chan job)
+	var wg sync.WaitGroup
+
+	workers := settings.Daemon.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				record := j.record
+				hooks := mergeHooks(config.Hooks, record.Hooks)
+
+				err := reconcileRecord(record, j.settings, hooks, failovers.targetIP(record, j.settings, hooks, j.pubIp))
+				if err != nil {
+					if breaker.failure(record, err, time.Now()) {
+						notify(hooks, j.settings, record, ErrorLog, "record-paused", "Record paused after permanent error. "+err.Error()+". Fix the record using update command to resume.")
+					}
+				} else {
+					breaker.success(record)
+				}
+			}
+		}()
+	}
+
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// reloadSettings returns defaults with settings of configuration read again by daemon.
+// Settings which apply to the whole process are kept as they were at start.
+func reloadSettings(start Settings, config *Settings) Settings {
+	settings := defaultSettings().overlay(config)
+
+	daemon := *settings.Daemon
+	daemon.PollInterval = start.Daemon.PollInterval
+	daemon.MaxBackoff = start.Daemon.MaxBackoff
+	daemon.MetricsListen = start.Daemon.MetricsListen
+	settings.Daemon = &daemon
+
+	httpSettings := *settings.HTTP
+	httpSettings.RateLimit = start.HTTP.RateLimit
+	settings.HTTP = &httpSettings
+
+	settings.Logging = start.Logging
+	return settings
+}
+
+// currentRecords returns A records of the name, and whether they were read from DNS. In
+// dns lookup mode they are read from authoritative name servers, which does not use GoDaddy
+// API quota. API is used when name servers do not give a consistent answer.
+func currentRecords(record DNSRecord, settings Settings) ([]godaddy.GodaddyRecordBody, bool, error) {
+	if settings.Daemon.Lookup == "dns" {
+		records, err := lookupAuthoritativeA(record.Domain, recordFqdn(record), time.Duration(settings.Daemon.DNSTimeout))
+		if err == nil {
+			daemonMetrics.add(metricRecordLookups, metricLabels("domain", record.Domain, "name", record.Name, "source", "dns"), 1)
+			return records, true, nil
+		}
+		GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "DNS lookup of record is inconclusive, using API. "+err.Error())
+	}
+
+	records, err := apiRecords(record, settings)
+	return records, false, err
+}
+
+// apiRecords returns A records of the name from GoDaddy API.
+func apiRecords(record DNSRecord, settings Settings) ([]godaddy.GodaddyRecordBody, error) {
+	daemonMetrics.add(metricRecordLookups, metricLabels("domain", record.Domain, "name", record.Name, "source", "api"), 1)
+
+	client := newGodaddyClient(settings.HTTP, record.Name, record.Domain, record.Key, record.Secret)
+	return client.GetRecords(context.Background(), record.Domain, godaddy.TypeA, record.Name)
+}
+
+// reconcileRecord updates the record in GoDaddy if its ip or ttl differs from desired state.
+func reconcileRecord(record DNSRecord, settings Settings, hooks *Hooks, pubIp string) error {
+	name := record.Name
+	domain := record.Domain
+	ttl := record.TTL
+
+	current, fromDNS, err := currentRecords(record, settings)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, name, domain, "Failed to get current state of record. "+err.Error())
+		return err
+	}
+
+	desired, existingIp, existingTtl, changed := planRecord(record, current, pubIp, lastIP(record))
+
+	// Name servers can agree on stale records for a while after a write, by this server
+	// or another host. DNS only tells that nothing changed, and updates are planned on
+	// records of API.
+	if changed && fromDNS {
+		current, err = apiRecords(record, settings)
+		if err != nil {
+			GoDaddyDDNSLogger(ErrorLog, name, domain, "Failed to get current state of record. "+err.Error())
+			return err
+		}
+		desired, existingIp, existingTtl, changed = planRecord(record, current, pubIp, lastIP(record))
+	}
+
+	if changed {
+		err := updateRecordWithHooks(record, settings, hooks, existingIp, existingTtl, pubIp, desired)
+		if errors.Is(err, ErrUpdateVetoed) {
+			return nil
+		} else if err != nil {
+			daemonMetrics.add(metricRecordUpdates, metricLabels("domain", domain, "name", name, "result", "failure"), 1)
+			GoDaddyDDNSLogger(ErrorLog, name, domain, "Failed to update record. "+err.Error())
+			return err
+		}
+		daemonMetrics.add(metricRecordUpdates, metricLabels("domain", domain, "name", name, "result", "success"), 1)
+		GoDaddyDDNSLogger(InformationLog, name, domain, "Record updated (ttl: "+fmt.Sprintf("%d", existingTtl)+"->"+fmt.Sprintf("%d", ttl)+", ip: "+existingIp+"->"+pubIp+")")
+
+		// GoDaddy accepts the update before its name servers serve it. Verification runs in
+		// background so that it does not hold the poll.
+		if *settings.Daemon.VerifyPropagation && pubIp != existingIp {
+			go verifyPropagation(record, pubIp, settings.Daemon)
+		}
+	} else {
+		saveLastIP(record, pubIp)
+		GoDaddyDDNSLogger(InformationLog, name, domain, "Desired state is current state")
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+func TestMergeRecord(t *testing.T) {
+	config := Configuration{Config: []DNSRecord{
+		{Domain: "example.com", Name: "home", TTL: 600, Strategy: StrategyMerge, Failover: &Failover{Backups: []string{"203.0.113.10"}}},
+		{Domain: "example.com", Name: "office", TTL: 600},
+	}}
+
+	_, _, err := mergeRecord(config, DNSRecord{Domain: "example.com", Name: "home"}, false, 10)
+	if !errors.Is(err, ErrRecordExists) {
+		t.Errorf("add of configured record: got %v, want ErrRecordExists", err)
+	}
+	_, _, err = mergeRecord(config, DNSRecord{Domain: "example.com", Name: "lab"}, true, 10)
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("update of missing record: got %v, want ErrRecordNotFound", err)
+	}
+	_, _, err = mergeRecord(config, DNSRecord{Domain: "example.com", Name: "lab"}, false, 2)
+	if !errors.Is(err, ErrRecordLimit) {
+		t.Errorf("add over limit: got %v, want ErrRecordLimit", err)
+	}
+
+	updated, record, err := mergeRecord(config, DNSRecord{Domain: "example.com", Name: "home", TTL: 60}, true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Strategy != StrategyMerge || record.Failover == nil {
+		t.Errorf("strategy and failover not kept on update: %+v", record)
+	}
+	if len(updated.Config) != 2 || len(config.Config) != 2 || config.Config[0].TTL != 600 {
+		t.Errorf("got records %+v, configuration changed to %+v", updated.Config, config.Config)
+	}
+	for _, configured := range updated.Config {
+		if configured.Name == "home" && configured.TTL != 60 {
+			t.Errorf("record not updated: %+v", configured)
+		}
+	}
+}
+
+// testAPI is GoDaddy API serving A records of one name and recording writes.
+type testAPI struct {
+	mu      sync.Mutex
+	records []godaddy.GodaddyRecordBody
+	writes  []string
+}
+
+func (api *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(api.records)
+	case "PUT":
+		var body []godaddy.GodaddyRecordBody
+		json.NewDecoder(r.Body).Decode(&body)
+		api.records = body
+		for _, record := range body {
+			api.writes = append(api.writes, r.Method+" "+record.Data)
+		}
+	default:
+		api.writes = append(api.writes, r.Method)
+	}
+}
+
+func TestReconcileStaleDNS(t *testing.T) {
+	t.Setenv("GODADDYDDNS_STATE_DIR", t.TempDir())
+	ns := startNameserver(t)
+	api := &testAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	settings := defaultSettings().overlay(&Settings{
+		Daemon: &DaemonSettings{Lookup: "dns", DNSTimeout: Duration(time.Second)},
+		HTTP:   &HTTPSettings{APIURL: server.URL},
+	})
+	record := DNSRecord{Domain: "example.com", Name: "home", TTL: 600, Key: "k", Secret: "s", Strategy: StrategyMerge}
+	saveLastIP(record, "198.51.100.1")
+
+	// Name servers still serve our previous IP only, while another host has added its IP
+	ns.set("home.example.com", "198.51.100.1")
+	api.records = []godaddy.GodaddyRecordBody{{Data: "198.51.100.1", TTL: 600}, {Data: "198.51.100.2", TTL: 600}}
+
+	err := reconcileRecord(record, settings, nil, "203.0.113.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(api.writes, ",") != "PUT 198.51.100.2,PUT 203.0.113.5" {
+		t.Errorf("got writes %v, want IP of other host kept", api.writes)
+	}
+
+	// Name servers have not caught up with our update on the next poll
+	api.writes = nil
+	err = reconcileRecord(record, settings, nil, "203.0.113.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.writes) != 0 {
+		t.Errorf("got writes %v, want none", api.writes)
+	}
+}
@@ -0,0 +1,140 @@
+// Package godaddy is a client for GoDaddy domains and DNS records API.
+//
+// Requests failing with network errors, 5xx or 429 responses are retried with
+// jittered exponential backoff honouring Retry-After sent by GoDaddy. PATCH requests
+// are retried only on 429 or connection failure, so that records are not added twice. Non-success
+// responses are returned as typed errors (AuthError, NotFoundError, RateLimitError, APIError).
+package godaddy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	DefaultBaseURL   string = "https://api.godaddy.com/v1"
+	DefaultUserAgent string = "godaddy-ddns"
+)
+
+// Client calls GoDaddy API using the key and secret generated from GoDaddy developer console.
+type Client struct {
+	HTTPClient *http.Client
+	BaseURL    string
+	Key        string
+	Secret     string
+	UserAgent  string
+
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	// Limiter is shared by clients which should not exceed GoDaddy rate limit together. Optional.
+	Limiter RateLimiter
+
+	// OnRetry is called before waiting for next attempt of a failed request. Optional.
+	OnRetry func(method, path string, delay time.Duration, reason string)
+}
+
+// NewClient returns client with default settings.
+func NewClient(key, secret string) *Client {
+	return &Client{
+		HTTPClient:     &http.Client{Timeout: 30 * time.Second},
+		BaseURL:        DefaultBaseURL,
+		Key:            key,
+		Secret:         secret,
+		UserAgent:      DefaultUserAgent,
+		MaxRetries:     4,
+		RetryBaseDelay: 1 * time.Second,
+		RetryMaxDelay:  1 * time.Minute,
+	}
+}
+
+// do sends the request and decodes successful JSON response into out, if not nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	bodyBytes, err := c.doWithRetry(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	if out == nil || len(bytes.TrimSpace(bodyBytes)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(bodyBytes, out)
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, []byte, error) {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	var requestBody *bytes.Reader
+	if body != nil {
+		requestBody = bytes.NewReader(body)
+	} else {
+		requestBody = bytes.NewReader([]byte{})
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, requestBody)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Add("Authorization", "sso-key "+c.Key+":"+c.Secret)
+	req.Header.Add("Accept", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, bodyBytes, nil
+}
+
+// pathEscape escapes each path segment.
+func pathEscape(segments ...string) string {
+	path := ""
+	for _, segment := range segments {
+		path += "/" + url.PathEscape(segment)
+	}
+	return path
+}
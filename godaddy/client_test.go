@@ -0,0 +1,245 @@
+package godaddy_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// testClient returns client of a GoDaddy API served by handler.
+func testClient(t *testing.T, handler http.HandlerFunc) *godaddy.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := godaddy.NewClient("key", "secret")
+	client.BaseURL = server.URL + "/v1"
+	client.RetryBaseDelay = time.Millisecond
+	client.RetryMaxDelay = 10 * time.Millisecond
+	client.MaxRetries = 3
+	return client
+}
+
+func TestGetRecords(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/v1/domains/example.com/records/A/home" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "sso-key key:secret" {
+			t.Errorf("unexpected Authorization header %q", auth)
+		}
+		w.Write([]byte(`[{"data":"203.0.113.1","name":"home","ttl":600,"type":"A"}]`))
+	})
+
+	records, err := client.GetRecords(context.Background(), "example.com", "A", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Data != "203.0.113.1" || records[0].TTL != 600 {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestGetRecordsOfName(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/domains/example.com/records" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`[{"data":"203.0.113.1","name":"home","ttl":600,"type":"A"},{"data":"example.com","name":"www","ttl":600,"type":"CNAME"},{"data":"v=spf1 -all","name":"home","ttl":600,"type":"TXT"}]`))
+	})
+
+	records, err := client.GetRecords(context.Background(), "example.com", "", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Type != "A" || records[1].Type != "TXT" {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestReplaceRecordsBody(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != "PUT" || r.URL.Path != "/v1/domains/example.com/records/MX/@" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if want := `[{"data":"mail.example.com","ttl":600,"priority":0}]`; string(body) != want {
+			t.Errorf("body %s, want %s", body, want)
+		}
+	})
+
+	err := client.ReplaceRecords(context.Background(), "example.com", "MX", "@", []godaddy.GodaddyRecordBody{
+		{Data: "mail.example.com", Name: "@", Type: "MX", TTL: 600, Priority: godaddy.Int(0)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetryIdempotentRequests(t *testing.T) {
+	var calls int32
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+
+	_, err := client.GetRecords(context.Background(), "example.com", "A", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d requests, want 3", calls)
+	}
+}
+
+func TestPatchNotRetriedOnServerError(t *testing.T) {
+	var calls int32
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	err := client.AddRecords(context.Background(), "example.com", []godaddy.GodaddyRecordBody{{Data: "203.0.113.1", Name: "home", Type: "A", TTL: 600}})
+	var apiErr *godaddy.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("got %v, want APIError with status 500", err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d requests, want 1", calls)
+	}
+}
+
+func TestPatchRetriedOnRateLimit(t *testing.T) {
+	var calls int32
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"code":"TOO_MANY_REQUESTS","message":"slow down"}`))
+			return
+		}
+	})
+
+	err := client.AddRecords(context.Background(), "example.com", []godaddy.GodaddyRecordBody{{Data: "203.0.113.1", Name: "home", Type: "A", TTL: 600}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("got %d requests, want 2", calls)
+	}
+}
+
+func TestPatchRetriedOnConnectError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	client := godaddy.NewClient("key", "secret")
+	client.BaseURL = "http://" + address + "/v1"
+	client.RetryBaseDelay = time.Millisecond
+	client.MaxRetries = 2
+	retries := 0
+	client.OnRetry = func(method, path string, delay time.Duration, reason string) { retries++ }
+
+	err = client.AddRecords(context.Background(), "example.com", []godaddy.GodaddyRecordBody{{Data: "203.0.113.1", Name: "home", Type: "A", TTL: 600}})
+	var networkErr *godaddy.NetworkError
+	if !errors.As(err, &networkErr) {
+		t.Fatalf("got %v, want NetworkError", err)
+	}
+	if retries != 2 {
+		t.Errorf("got %d retries, want 2", retries)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		check  func(error) bool
+	}{
+		{http.StatusUnauthorized, `{"code":"UNABLE_TO_AUTHENTICATE"}`, func(err error) bool {
+			var target *godaddy.AuthError
+			return errors.As(err, &target)
+		}},
+		{http.StatusForbidden, ``, func(err error) bool {
+			var target *godaddy.AuthError
+			return errors.As(err, &target)
+		}},
+		{http.StatusNotFound, `{"code":"UNKNOWN_DOMAIN","message":"not found"}`, func(err error) bool {
+			var target *godaddy.NotFoundError
+			return errors.As(err, &target) && target.Code == "UNKNOWN_DOMAIN"
+		}},
+		{http.StatusTooManyRequests, `{"code":"TOO_MANY_REQUESTS","retryAfterSec":30}`, func(err error) bool {
+			var target *godaddy.RateLimitError
+			return errors.As(err, &target) && target.RetryAfter == 30*time.Second
+		}},
+		{http.StatusUnprocessableEntity, `{"code":"INVALID_BODY","fields":[{"path":"records[0].ttl","message":"too low"}]}`, func(err error) bool {
+			var target *godaddy.APIError
+			return errors.As(err, &target) && len(target.Fields) == 1
+		}},
+	}
+
+	for _, test := range tests {
+		client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		})
+		client.MaxRetries = 0
+
+		_, err := client.GetRecords(context.Background(), "example.com", "A", "home")
+		if !test.check(err) {
+			t.Errorf("status %d: unexpected error %#v", test.status, err)
+		}
+	}
+}
+
+func TestValidateRecord(t *testing.T) {
+	tests := []struct {
+		record godaddy.GodaddyRecordBody
+		valid  bool
+	}{
+		{godaddy.GodaddyRecordBody{Type: "A", Data: "203.0.113.1", TTL: 600}, true},
+		{godaddy.GodaddyRecordBody{Type: "A", Data: "2001:db8::1", TTL: 600}, false},
+		{godaddy.GodaddyRecordBody{Type: "A", Data: "203.0.113.1", TTL: 300}, false},
+		{godaddy.GodaddyRecordBody{Type: "AAAA", Data: "2001:db8::1"}, true},
+		{godaddy.GodaddyRecordBody{Type: "MX", Data: "mail.example.com", Priority: godaddy.Int(0)}, true},
+		{godaddy.GodaddyRecordBody{Type: "SRV", Data: "sip.example.com", Service: "_sip", Protocol: "_tcp", Port: godaddy.Int(5060), Priority: godaddy.Int(0), Weight: godaddy.Int(0)}, true},
+		{godaddy.GodaddyRecordBody{Type: "SRV", Data: "sip.example.com", Service: "sip", Protocol: "_tcp", Port: godaddy.Int(5060)}, false},
+		{godaddy.GodaddyRecordBody{Type: "SRV", Data: "sip.example.com", Service: "_sip", Protocol: "_tcp"}, false},
+		{godaddy.GodaddyRecordBody{Type: "CAA", Data: `0 issue "letsencrypt.org"`}, true},
+		{godaddy.GodaddyRecordBody{Type: "CAA", Data: "letsencrypt.org"}, false},
+		{godaddy.GodaddyRecordBody{Type: "SPF", Data: "v=spf1 -all"}, false},
+	}
+
+	for _, test := range tests {
+		err := godaddy.ValidateRecord(test.record)
+		if (err == nil) != test.valid {
+			t.Errorf("ValidateRecord(%+v) = %v, want valid %v", test.record, err, test.valid)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := godaddy.NewRateLimiter(10, 200*time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("3 requests took %s, want at least 40ms", elapsed)
+	}
+}
@@ -0,0 +1,25 @@
+package godaddy
+
+import (
+	"context"
+)
+
+// ListDomains returns domains in the GoDaddy account.
+func (c *Client) ListDomains(ctx context.Context) ([]Domain, error) {
+	var domains []Domain
+	err := c.do(ctx, "GET", pathEscape("domains"), nil, &domains)
+	if err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// GetDomain returns details of the domain.
+func (c *Client) GetDomain(ctx context.Context, domain string) (*Domain, error) {
+	var details Domain
+	err := c.do(ctx, "GET", pathEscape("domains", domain), nil, &details)
+	if err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
@@ -0,0 +1,90 @@
+package godaddy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIError is returned when GoDaddy responds with a non-success status.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Fields     []GodaddyErrorField
+}
+
+func (err *APIError) Error() string {
+	msg := fmt.Sprintf("GoDaddy API error, StatusCode: %d", err.StatusCode)
+	if err.Code != "" {
+		msg += ", Code: " + err.Code
+	}
+	if err.Message != "" {
+		msg += ", Message: " + err.Message
+	}
+	for _, field := range err.Fields {
+		msg += fmt.Sprintf(" [%s: %s]", field.Path, field.Message)
+	}
+	return msg
+}
+
+// AuthError is returned when GoDaddy rejects the key and secret (401) or
+// the key has no access to the domain (403).
+type AuthError struct {
+	*APIError
+}
+
+func (err *AuthError) Unwrap() error { return err.APIError }
+
+// NotFoundError is returned when domain or record does not exist in GoDaddy account.
+type NotFoundError struct {
+	*APIError
+}
+
+func (err *NotFoundError) Unwrap() error { return err.APIError }
+
+// RateLimitError is returned when GoDaddy keeps throttling the requests after all retries.
+type RateLimitError struct {
+	*APIError
+	RetryAfter time.Duration
+}
+
+func (err *RateLimitError) Unwrap() error { return err.APIError }
+
+// NetworkError is returned when GoDaddy could not be reached.
+type NetworkError struct {
+	Err error
+}
+
+func (err *NetworkError) Error() string {
+	return "network error, " + err.Err.Error()
+}
+
+func (err *NetworkError) Unwrap() error { return err.Err }
+
+// newAPIError builds typed error from GoDaddy error response.
+func newAPIError(statusCode int, bodyBytes []byte, retryAfter time.Duration) error {
+	var errorBody GodaddyErrorBody
+	if err := json.Unmarshal(bodyBytes, &errorBody); err != nil || errorBody.Message == "" {
+		errorBody.Message = strings.TrimSpace(http.StatusText(statusCode))
+	}
+
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Code:       errorBody.Code,
+		Message:    errorBody.Message,
+		Fields:     errorBody.Fields,
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return &AuthError{apiErr}
+	case statusCode == http.StatusNotFound:
+		return &NotFoundError{apiErr}
+	case statusCode == http.StatusTooManyRequests:
+		return &RateLimitError{APIError: apiErr, RetryAfter: retryAfter}
+	}
+	return apiErr
+}
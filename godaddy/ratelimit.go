@@ -0,0 +1,52 @@
+package godaddy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter blocks until next request is allowed.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// IntervalLimiter spaces out requests evenly so that at most limit requests are sent per period.
+type IntervalLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter returns limiter allowing limit requests per period.
+// GoDaddy allows 60 requests per minute.
+func NewRateLimiter(limit int, period time.Duration) *IntervalLimiter {
+	if limit < 1 {
+		limit = 1
+	}
+	return &IntervalLimiter{interval: period / time.Duration(limit)}
+}
+
+func (l *IntervalLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
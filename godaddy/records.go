@@ -0,0 +1,62 @@
+package godaddy
+
+import (
+	"context"
+	"strings"
+)
+
+// GetRecords returns records of the domain. Empty recordType returns records of all types
+// and empty name returns records of all names.
+func (c *Client) GetRecords(ctx context.Context, domain, recordType, name string) ([]GodaddyRecordBody, error) {
+	path := pathEscape("domains", domain, "records")
+	if recordType != "" {
+		path += pathEscape(recordType)
+		if name != "" {
+			path += pathEscape(name)
+		}
+	}
+
+	var records []GodaddyRecordBody
+	err := c.do(ctx, "GET", path, nil, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	// GoDaddy API has no endpoint for all types of a name
+	if recordType == "" && name != "" {
+		named := records[:0]
+		for _, record := range records {
+			if strings.EqualFold(record.Name, name) {
+				named = append(named, record)
+			}
+		}
+		records = named
+	}
+	return records, nil
+}
+
+// ReplaceRecords replaces all records of the type and name with records.
+// Name and Type of records are taken from the arguments.
+func (c *Client) ReplaceRecords(ctx context.Context, domain, recordType, name string, records []GodaddyRecordBody) error {
+	body := make([]GodaddyRecordBody, len(records))
+	for i, record := range records {
+		record.Name = ""
+		record.Type = ""
+		body[i] = record
+	}
+
+	path := pathEscape("domains", domain, "records", recordType, name)
+	return c.do(ctx, "PUT", path, body, nil)
+}
+
+// AddRecords adds records to the domain without touching existing records.
+func (c *Client) AddRecords(ctx context.Context, domain string, records []GodaddyRecordBody) error {
+	path := pathEscape("domains", domain, "records")
+	return c.do(ctx, "PATCH", path, records, nil)
+}
+
+// DeleteRecords deletes all records of the type and name.
+func (c *Client) DeleteRecords(ctx context.Context, domain, recordType, name string) error {
+	path := pathEscape("domains", domain, "records", recordType, name)
+	return c.do(ctx, "DELETE", path, nil, nil)
+}
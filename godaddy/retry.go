@@ -0,0 +1,145 @@
+package godaddy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Source of backoff jitter. Global source of math/rand is left to importers.
+var (
+	jitterMutex sync.Mutex
+	jitter      = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// doWithRetry sends request to GoDaddy API retrying network errors, 5xx and 429 responses.
+// PATCH and POST are not idempotent and are retried only on 429 or when connection could
+// not be made, as the request may have been applied before a network error or 5xx.
+// Non-success response after all retries is returned as typed error.
+func (c *Client) doWithRetry(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	var (
+		response   *http.Response
+		bodyBytes  []byte
+		retryAfter time.Duration
+		err        error
+	)
+
+	for attempt := 0; ; attempt++ {
+		retryAfter = 0
+		response, bodyBytes, err = c.send(ctx, method, path, body)
+		if err == nil {
+			retryAfter = retryAfterDelay(response, bodyBytes)
+		}
+
+		if !isRetryable(method, response, err) {
+			break
+		}
+
+		if ctx.Err() != nil || attempt >= c.MaxRetries {
+			break
+		}
+
+		delay := c.backoffDelay(attempt)
+		if retryAfter > 0 {
+			if c.RetryMaxDelay > 0 && retryAfter > c.RetryMaxDelay {
+				break
+			}
+			delay = retryAfter
+		}
+
+		if c.OnRetry != nil {
+			reason := ""
+			if err != nil {
+				reason = err.Error()
+			} else {
+				reason = fmt.Sprintf("status %d", response.StatusCode)
+			}
+			c.OnRetry(method, path, delay, reason)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+
+	if err != nil {
+		return nil, &NetworkError{Err: err}
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, newAPIError(response.StatusCode, bodyBytes, retryAfter)
+	}
+
+	return bodyBytes, nil
+}
+
+func isRetryable(method string, response *http.Response, err error) bool {
+	idempotent := method != "PATCH" && method != "POST"
+	if err != nil {
+		return idempotent || isConnectError(err)
+	}
+	if response.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return idempotent && response.StatusCode >= 500
+}
+
+// isConnectError reports if the request failed before connection to GoDaddy was made.
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryAfterDelay reads Retry-After header (seconds or HTTP date) or retryAfterSec
+// from GoDaddy TOO_MANY_REQUESTS error body.
+func retryAfterDelay(response *http.Response, bodyBytes []byte) time.Duration {
+	if value := response.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			if delay := time.Until(date); delay > 0 {
+				return delay
+			}
+		}
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		var errorBody GodaddyErrorBody
+		if err := json.Unmarshal(bodyBytes, &errorBody); err == nil && errorBody.RetryAfterSec > 0 {
+			return time.Duration(errorBody.RetryAfterSec) * time.Second
+		}
+	}
+
+	return 0
+}
+
+// backoffDelay returns exponential delay for the attempt with random jitter of upto half the delay.
+func (c *Client) backoffDelay(attempt int) time.Duration {
+	maxDelay := c.RetryMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = time.Minute
+	}
+
+	delay := maxDelay
+	if attempt < 30 {
+		delay = c.RetryBaseDelay << uint(attempt)
+	}
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	half := delay / 2
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+	return half + time.Duration(jitter.Int63n(int64(half)+1))
+}
@@ -0,0 +1,67 @@
+package godaddy
+
+// Record types supported by GoDaddy DNS.
+const (
+	TypeA     string = "A"
+	TypeAAAA  string = "AAAA"
+	TypeCNAME string = "CNAME"
+	TypeTXT   string = "TXT"
+	TypeMX    string = "MX"
+	TypeSRV   string = "SRV"
+	TypeCAA   string = "CAA"
+	TypeNS    string = "NS"
+	TypeSOA   string = "SOA"
+)
+
+// RecordTypes are the record types which can be managed using this package.
+var RecordTypes = []string{TypeA, TypeAAAA, TypeCNAME, TypeTXT, TypeMX, TypeSRV, TypeCAA, TypeNS}
+
+// GodaddyRecordBody is a DNS record. Priority is used by MX and SRV records.
+// Service, Protocol, Port and Weight are used by SRV records only. Priority, Port
+// and Weight are pointers, as 0 is a valid value which must be sent to GoDaddy.
+type GodaddyRecordBody struct {
+	Data     string `json:"data" yaml:"data"`
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	TTL      int    `json:"ttl" yaml:"ttl"`
+	Type     string `json:"type,omitempty" yaml:"type,omitempty"`
+	Priority *int   `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Service  string `json:"service,omitempty" yaml:"service,omitempty"`
+	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Port     *int   `json:"port,omitempty" yaml:"port,omitempty"`
+	Weight   *int   `json:"weight,omitempty" yaml:"weight,omitempty"`
+}
+
+// Int returns pointer to v, for Priority, Port and Weight of records.
+func Int(v int) *int {
+	return &v
+}
+
+// IntValue returns value of Priority, Port or Weight. Field which is not set is 0.
+func IntValue(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+type GodaddyErrorField struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Path    string `json:"path"`
+}
+
+type GodaddyErrorBody struct {
+	Code          string              `json:"code"`
+	Message       string              `json:"message"`
+	Fields        []GodaddyErrorField `json:"fields"`
+	RetryAfterSec int                 `json:"retryAfterSec,omitempty"`
+}
+
+// Domain is a domain in GoDaddy account.
+type Domain struct {
+	Domain      string   `json:"domain"`
+	DomainID    int64    `json:"domainId"`
+	Status      string   `json:"status"`
+	Expires     string   `json:"expires,omitempty"`
+	NameServers []string `json:"nameServers"`
+}
@@ -0,0 +1,62 @@
+package godaddy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IsRecordType reports if recordType can be managed using this package.
+func IsRecordType(recordType string) bool {
+	for _, t := range RecordTypes {
+		if t == recordType {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRecord checks that the record has the fields required by its type.
+func ValidateRecord(record GodaddyRecordBody) error {
+	if !IsRecordType(record.Type) {
+		return fmt.Errorf("unsupported record type %q. supported types are %s", record.Type, strings.Join(RecordTypes, ", "))
+	}
+	if record.Data == "" {
+		return errors.New("data is required")
+	}
+	if record.TTL != 0 && record.TTL < 600 {
+		return errors.New("ttl cannot be less than 600 seconds")
+	}
+
+	switch record.Type {
+	case TypeA:
+		if ip := net.ParseIP(record.Data); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("invalid IPv4 address %q", record.Data)
+		}
+	case TypeAAAA:
+		if ip := net.ParseIP(record.Data); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("invalid IPv6 address %q", record.Data)
+		}
+	case TypeMX:
+		if priority := IntValue(record.Priority); priority < 0 || priority > 65535 {
+			return errors.New("priority must be between 0 and 65535")
+		}
+	case TypeSRV:
+		if !strings.HasPrefix(record.Service, "_") || !strings.HasPrefix(record.Protocol, "_") {
+			return errors.New("service and protocol are required and must start with '_'. e.g. _sip, _tcp")
+		}
+		if port := IntValue(record.Port); port < 1 || port > 65535 {
+			return errors.New("port must be between 1 and 65535")
+		}
+		weight, priority := IntValue(record.Weight), IntValue(record.Priority)
+		if weight < 0 || weight > 65535 || priority < 0 || priority > 65535 {
+			return errors.New("priority and weight must be between 0 and 65535")
+		}
+	case TypeCAA:
+		if len(strings.Fields(record.Data)) < 3 {
+			return fmt.Errorf("invalid CAA data %q. expected format: <flags> <tag> <value>. e.g. 0 issue \"letsencrypt.org\"", record.Data)
+		}
+	}
+	return nil
+}
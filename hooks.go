@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+const (
+	PreUpdateHook  string = "pre-update"
+	PostUpdateHook string = "post-update"
+	NotifyHook     string = "notify"
+)
+
+var default_hook_timeout time.Duration = 30 * time.Second
+
+// Hook is a shell command executed around a record update.
+type Hook struct {
+	Command string `json:"command"`
+	Timeout int    `json:"timeout,omitempty"` // Time in seconds
+	Veto    bool   `json:"veto,omitempty"`    // Only for pre-update hook. Non-zero exit cancels the update
+}
+
+// Hooks can be set globally in configuration or per record. Record hooks take precedence.
+type Hooks struct {
+	PreUpdate  *Hook `json:"pre_update,omitempty"`
+	PostUpdate *Hook `json:"post_update,omitempty"`
+	Notify     *Hook `json:"notify,omitempty"` // Alerts like record paused due to permanent errors
+}
+
+// HookEvent carries the details of an update which are passed to hook as environment variables.
+type HookEvent struct {
+	Name    string
+	Domain  string
+	OldIP   string
+	NewIP   string
+	OldTTL  int
+	TTL     int
+	Event   string // Set only for notify hook
+	Message string
+}
+
+// mergeHooks returns record hooks with missing hooks filled from global hooks.
+func mergeHooks(global, record *Hooks) *Hooks {
+	merged := &Hooks{}
+	if global != nil {
+		merged.PreUpdate = global.PreUpdate
+		merged.PostUpdate = global.PostUpdate
+		merged.Notify = global.Notify
+	}
+	if record != nil {
+		if record.PreUpdate != nil {
+			merged.PreUpdate = record.PreUpdate
+		}
+		if record.PostUpdate != nil {
+			merged.PostUpdate = record.PostUpdate
+		}
+		if record.Notify != nil {
+			merged.Notify = record.Notify
+		}
+	}
+	return merged
+}
+
+func runHook(stage string, hook *Hook, event HookEvent) error {
+	if hook == nil || strings.TrimSpace(hook.Command) == "" {
+		return nil
+	}
+
+	timeout := default_hook_timeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Second
+	}
+
+	cmd := exec.Command("sh", "-c", hook.Command)
+	setHookProcAttr(cmd)
+	cmd.Env = append(os.Environ(),
+		"GD_HOOK="+stage,
+		"GD_NAME="+event.Name,
+		"GD_DOMAIN="+event.Domain,
+		"GD_OLD_IP="+event.OldIP,
+		"GD_NEW_IP="+event.NewIP,
+		"GD_OLD_TTL="+fmt.Sprintf("%d", event.OldTTL),
+		"GD_TTL="+fmt.Sprintf("%d", event.TTL),
+		"GD_EVENT="+event.Event,
+		"GD_MESSAGE="+event.Message,
+	)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Start()
+	if err != nil {
+		return &HookError{Stage: stage, ExitCode: -1, Err: fmt.Errorf("failed to run %w", err)}
+	}
+
+	waitDone := make(chan error, 1)
+	go func() {
+		waitDone <- cmd.Wait()
+	}()
+
+	timedOut := false
+	select {
+	case err = <-waitDone:
+	case <-time.After(timeout):
+		timedOut = true
+		killHook(cmd)
+		err = <-waitDone
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line != "" {
+			GoDaddyDDNSLogger(InformationLog, event.Name, event.Domain, stage+" hook: "+line)
+		}
+	}
+
+	if timedOut {
+		return &HookError{Stage: stage, ExitCode: -1, Err: errors.New("timed out after " + timeout.String())}
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &HookError{Stage: stage, ExitCode: exitErr.ExitCode(), Err: fmt.Errorf("exited with status %d", exitErr.ExitCode())}
+	}
+	if err != nil {
+		return &HookError{Stage: stage, ExitCode: -1, Err: fmt.Errorf("failed %w", err)}
+	}
+
+	return nil
+}
+
+// updateRecordWithHooks runs pre-update hook, updates the record in GoDaddy and runs post-update hook.
+// If pre-update hook fails and has veto enabled, record is not updated and ErrUpdateVetoed is returned.
+func updateRecordWithHooks(record DNSRecord, settings Settings, hooks *Hooks, existingIp string, existingTtl int, pubIp string, desired []godaddy.GodaddyRecordBody) error {
+	event := HookEvent{
+		Name:   record.Name,
+		Domain: record.Domain,
+		OldIP:  existingIp,
+		NewIP:  pubIp,
+		OldTTL: existingTtl,
+		TTL:    record.TTL,
+	}
+
+	if hooks == nil {
+		hooks = &Hooks{}
+	}
+
+	err := runHook(PreUpdateHook, hooks.PreUpdate, event)
+	if err != nil {
+		if hooks.PreUpdate.Veto {
+			GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, err.Error()+". Skipping update.")
+			return ErrUpdateVetoed
+		}
+		GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, err.Error()+". Continuing with update.")
+	}
+
+	client := newGodaddyClient(settings.HTTP, record.Name, record.Domain, record.Key, record.Secret)
+	if record.Strategy == StrategyMerge && existingIp == "" {
+		// No IP of ours is to be removed. Adding only our IP keeps A records
+		// written by other hosts since current records were read.
+		own := godaddy.GodaddyRecordBody{Type: godaddy.TypeA, Name: record.Name, Data: pubIp, TTL: record.TTL}
+		err = client.AddRecords(context.Background(), record.Domain, []godaddy.GodaddyRecordBody{own})
+	} else {
+		err = client.ReplaceRecords(context.Background(), record.Domain, godaddy.TypeA, record.Name, desired)
+	}
+	if err != nil {
+		return err
+	}
+	saveLastIP(record, pubIp)
+
+	err = runHook(PostUpdateHook, hooks.PostUpdate, event)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, record.Name, record.Domain, err.Error())
+	}
+
+	return nil
+}
+
+// notify logs the event and runs notify hook if configured for the event.
+func notify(hooks *Hooks, settings Settings, record DNSRecord, logType, event, message string) {
+	GoDaddyDDNSLogger(logType, record.Name, record.Domain, message)
+
+	if hooks == nil || hooks.Notify == nil || !settings.Notifications.enabled(event) {
+		return
+	}
+
+	err := runHook(NotifyHook, hooks.Notify, HookEvent{
+		Name:    record.Name,
+		Domain:  record.Domain,
+		TTL:     record.TTL,
+		Event:   event,
+		Message: message,
+	})
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, record.Name, record.Domain, err.Error())
+	}
+}
@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os/exec"
+
+func setHookProcAttr(cmd *exec.Cmd) {}
+
+func killHook(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,105 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	secretServiceName   string = "org.freedesktop.secrets"
+	secretServicePath   string = "/org/freedesktop/secrets"
+	secretServiceIface  string = "org.freedesktop.Secret.Service"
+	secretItemIface     string = "org.freedesktop.Secret.Item"
+	secretSessionIface  string = "org.freedesktop.Secret.Session"
+	keyringApplication  string = "godaddy-ddns"
+	kernelKeyringPrefix string = "kernel:"
+)
+
+// secretValue is the Secret struct of Secret Service API.
+type secretValue struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// keyringSecret reads the secret of keyring:<name> reference. kernel:<name> is read from
+// kernel user keyring, other names from Secret Service (GNOME Keyring, KWallet).
+func keyringSecret(name string) (string, error) {
+	if strings.HasPrefix(name, kernelKeyringPrefix) {
+		return kernelKeyringSecret(strings.TrimPrefix(name, kernelKeyringPrefix))
+	}
+	return secretServiceSecret(name)
+}
+
+// secretServiceSecret looks up the item with attributes application=godaddy-ddns and
+// name=<name> in Secret Service of the session bus.
+func secretServiceSecret(name string) (string, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return "", fmt.Errorf("cannot connect to session bus %w", err)
+	}
+
+	service := conn.Object(secretServiceName, dbus.ObjectPath(secretServicePath))
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err = service.Call(secretServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session)
+	if err != nil {
+		return "", fmt.Errorf("cannot open Secret Service session %w", err)
+	}
+	defer conn.Object(secretServiceName, session).Call(secretSessionIface+".Close", 0)
+
+	attributes := map[string]string{"application": keyringApplication, "name": name}
+	var unlocked, locked []dbus.ObjectPath
+	err = service.Call(secretServiceIface+".SearchItems", 0, attributes).Store(&unlocked, &locked)
+	if err != nil {
+		return "", fmt.Errorf("cannot search Secret Service %w", err)
+	}
+
+	if len(unlocked) == 0 {
+		if len(locked) != 0 {
+			return "", errors.New("keyring item " + name + " is locked. Unlock the keyring and retry")
+		}
+		return "", errors.New("no keyring item with application=" + keyringApplication + " and name=" + name)
+	}
+
+	var secret secretValue
+	err = conn.Object(secretServiceName, unlocked[0]).Call(secretItemIface+".GetSecret", 0, session).Store(&secret)
+	if err != nil {
+		return "", fmt.Errorf("cannot read keyring item %w", err)
+	}
+	return string(secret.Value), nil
+}
+
+// kernelKeyringSecret reads the user key described godaddy-ddns:<name> from user keyring,
+// or else from session keyring.
+func kernelKeyringSecret(name string) (string, error) {
+	description := keyringApplication + ":" + name
+
+	id, err := unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, "user", description, 0)
+	if err != nil {
+		id, err = unix.KeyctlSearch(unix.KEY_SPEC_SESSION_KEYRING, "user", description, 0)
+	}
+	if err != nil {
+		return "", fmt.Errorf("no kernel key %s %w", description, err)
+	}
+
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return "", fmt.Errorf("cannot read kernel key %s %w", description, err)
+	}
+	buffer := make([]byte, size)
+	size, err = unix.KeyctlBuffer(unix.KEYCTL_READ, id, buffer, 0)
+	if err != nil {
+		return "", fmt.Errorf("cannot read kernel key %s %w", description, err)
+	}
+	return string(buffer[:size]), nil
+}
@@ -0,0 +1,106 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"golang.org/x/sys/unix"
+)
+
+// TestSecretServiceKeyring runs itself again in a new session bus with GNOME Keyring,
+// stores an item of godaddy-ddns and resolves its keyring:NAME reference.
+func TestSecretServiceKeyring(t *testing.T) {
+	if os.Getenv("GODADDYDDNS_TEST_SESSION_BUS") == "" {
+		for _, tool := range []string{"dbus-run-session", "gnome-keyring-daemon"} {
+			if _, err := exec.LookPath(tool); err != nil {
+				t.Skip(tool + " is not available")
+			}
+		}
+
+		// Empty password on stdin creates the login keyring unlocked
+		script := `echo -n | gnome-keyring-daemon --unlock --components=secrets >/dev/null && exec "$0" -test.run='^TestSecretServiceKeyring$' -test.v`
+		cmd := exec.Command("dbus-run-session", "--", "sh", "-c", script, os.Args[0])
+		cmd.Env = append(os.Environ(), "GODADDYDDNS_TEST_SESSION_BUS=1")
+		output, err := cmd.CombinedOutput()
+		t.Logf("%s", output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	storeKeyringItem(t, "home", "s3cRe7")
+
+	secret, err := resolveSecret("keyring:home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != "s3cRe7" {
+		t.Errorf("got %q, want s3cRe7", secret)
+	}
+
+	_, err = resolveSecret("keyring:missing")
+	if err == nil {
+		t.Errorf("keyring:missing resolved without keyring item")
+	}
+}
+
+// storeKeyringItem creates item with attributes application=godaddy-ddns and name in
+// default collection of Secret Service.
+func storeKeyringItem(t *testing.T, name string, value string) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	service := conn.Object(secretServiceName, dbus.ObjectPath(secretServicePath))
+	err = service.Call(secretServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	properties := map[string]dbus.Variant{
+		secretItemIface + ".Label":      dbus.MakeVariant("godaddy-ddns " + name),
+		secretItemIface + ".Attributes": dbus.MakeVariant(map[string]string{"application": keyringApplication, "name": name}),
+	}
+	secret := secretValue{Session: session, Parameters: []byte{}, Value: []byte(value), ContentType: "text/plain"}
+
+	var item, prompt dbus.ObjectPath
+	for _, path := range []string{"/org/freedesktop/secrets/aliases/default", "/org/freedesktop/secrets/collection/login"} {
+		err = conn.Object(secretServiceName, dbus.ObjectPath(path)).Call("org.freedesktop.Secret.Collection.CreateItem", 0, properties, secret, true).Store(&item, &prompt)
+		if err == nil {
+			return
+		}
+	}
+	t.Fatalf("cannot create keyring item %v", err)
+}
+
+func TestKernelKeyring(t *testing.T) {
+	name := fmt.Sprintf("test-%d", os.Getpid())
+	id, err := unix.AddKey("user", keyringApplication+":"+name, []byte("s3cRe7"), unix.KEY_SPEC_SESSION_KEYRING)
+	if err != nil {
+		t.Skip("cannot add key to session keyring: " + err.Error())
+	}
+	t.Cleanup(func() { unix.KeyctlInt(unix.KEYCTL_UNLINK, id, unix.KEY_SPEC_SESSION_KEYRING, 0, 0) })
+
+	secret, err := resolveSecret("keyring:kernel:" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != "s3cRe7" {
+		t.Errorf("got %q, want s3cRe7", secret)
+	}
+
+	_, err = resolveSecret("keyring:kernel:" + name + "-missing")
+	if err == nil {
+		t.Errorf("missing kernel key resolved")
+	}
+}
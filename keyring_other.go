@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "errors"
+
+func keyringSecret(name string) (string, error) {
+	return "", errors.New("keyring references are supported only on linux")
+}
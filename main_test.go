@@ -0,0 +1,22 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestMain keeps tests away from configuration, state and log file of the user.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "godaddy-ddns-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("GODADDYDDNS_CONFIG", dir+"/config.json")
+	os.Setenv("GODADDYDDNS_STATE_DIR", dir+"/state")
+	configureLogging(&LoggingSettings{File: "off", Level: "error"})
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	metricRecordUpdates      string = "godaddyddns_record_updates_total"
+	metricPropagationChecks  string = "godaddyddns_propagation_checks_total"
+	metricPropagationSeconds string = "godaddyddns_propagation_seconds"
+	metricRecordLookups      string = "godaddyddns_record_lookups_total"
+	metricFailoverActive     string = "godaddyddns_failover_active"
+)
+
+var metricHelp = map[string][2]string{
+	metricRecordUpdates:      {"counter", "Record updates sent to GoDaddy by result."},
+	metricPropagationChecks:  {"counter", "Propagation verifications against authoritative name servers by result."},
+	metricRecordLookups:      {"counter", "Lookups of current record state by source."},
+	metricFailoverActive:     {"gauge", "1 if record points to backup IP, 0 if it points to public IP."},
+	metricPropagationSeconds: {"gauge", "Seconds taken by last update to be served by all authoritative name servers."},
+}
+
+// metricsRegistry holds daemon metrics and exposes them in Prometheus text format.
+type metricsRegistry struct {
+	mu     sync.Mutex
+	values map[string]map[string]float64
+}
+
+var daemonMetrics = &metricsRegistry{values: make(map[string]map[string]float64)}
+
+// metricLabels formats labels as name="value" pairs. Pairs are passed as name, value, ...
+func metricLabels(pairs ...string) string {
+	var labels []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1])
+		labels = append(labels, pairs[i]+`="`+value+`"`)
+	}
+	return strings.Join(labels, ",")
+}
+
+func (m *metricsRegistry) add(name, labels string, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values[name] == nil {
+		m.values[name] = make(map[string]float64)
+	}
+	m.values[name][labels] += delta
+}
+
+func (m *metricsRegistry) set(name, labels string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values[name] == nil {
+		m.values[name] = make(map[string]float64)
+	}
+	m.values[name][labels] = value
+}
+
+// ServeHTTP writes metrics to the client after releasing the lock, so that a slow client
+// does not hold up the poll updating them.
+func (m *metricsRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(m.text())
+}
+
+// text returns metrics in Prometheus text format.
+func (m *metricsRegistry) text() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	for name := range m.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		help := metricHelp[name]
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", name, help[1], name, help[0])
+
+		var series []string
+		for labels := range m.values[name] {
+			series = append(series, labels)
+		}
+		sort.Strings(series)
+		for _, labels := range series {
+			fmt.Fprintf(&buf, "%s{%s} %g\n", name, labels, m.values[name][labels])
+		}
+	}
+	return buf.Bytes()
+}
+
+// serveMetrics exposes daemon metrics on /metrics of address.
+func serveMetrics(address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", daemonMetrics)
+
+	GoDaddyDDNSLogger(InformationLog, "", "", "Serving metrics on "+address+"/metrics")
+	err := http.ListenAndServe(address, mux)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to serve metrics. "+err.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stalledWriter blocks writes until release is closed, like a client not reading the response.
+// writing receives when a write is blocked.
+type stalledWriter struct {
+	*httptest.ResponseRecorder
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (w stalledWriter) Write(b []byte) (int, error) {
+	select {
+	case w.writing <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestMetricsStalledScraper(t *testing.T) {
+	metrics := &metricsRegistry{values: make(map[string]map[string]float64)}
+	metrics.add(metricRecordUpdates, metricLabels("domain", "example.com", "name", "home", "result", "success"), 1)
+
+	w := stalledWriter{httptest.NewRecorder(), make(chan struct{}, 1), make(chan struct{})}
+	served := make(chan struct{})
+	go func() {
+		metrics.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		close(served)
+	}()
+	<-w.writing
+
+	added := make(chan struct{})
+	go func() {
+		metrics.add(metricRecordUpdates, metricLabels("domain", "example.com", "name", "home", "result", "success"), 1)
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(5 * time.Second):
+		t.Error("metrics update waited for stalled scraper")
+	}
+
+	close(w.release)
+	<-served
+	if !strings.Contains(w.Body.String(), metricRecordUpdates+`{domain="example.com",name="home",result="success"}`) {
+		t.Errorf("got metrics %q", w.Body.String())
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// currentConfigVersion is the layout of configuration written by this release. Configuration
+// without version field is version 1, the layout of v1.0 and v1.1.
+const currentConfigVersion int = 2
+
+// configMigrations[i] upgrades configuration from version i+1 to i+2. Migrations work on
+// the parsed file, as older layouts may not decode into current structs.
+var configMigrations = []func(config map[string]interface{}) error{
+	migrateV1,
+}
+
+// migrateV1 upgrades layout of v1.0 and v1.1. Records were stored under Config, and ttl
+// was a string in the configuration template.
+func migrateV1(config map[string]interface{}) error {
+	for key, value := range config {
+		if key != "config" && strings.EqualFold(key, "config") {
+			delete(config, key)
+			config["config"] = value
+		}
+	}
+
+	records, _ := config["config"].([]interface{})
+	for i, item := range records {
+		record, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range record {
+			if lower := strings.ToLower(key); lower != key {
+				delete(record, key)
+				record[lower] = value
+			}
+		}
+		if ttl, ok := record["ttl"].(string); ok {
+			value, err := strconv.Atoi(strings.TrimSpace(ttl))
+			if err != nil {
+				return fmt.Errorf("config[%d].ttl %q is not a number of seconds", i, ttl)
+			}
+			record["ttl"] = value
+		}
+	}
+	return nil
+}
+
+// configVersion returns version field of parsed configuration. Missing version is 1.
+func configVersion(config map[string]interface{}) (int, error) {
+	value, ok := config["version"]
+	if !ok {
+		return 1, nil
+	}
+	var version int
+	switch v := value.(type) {
+	case int:
+		version = v
+	case int64:
+		version = int(v)
+	}
+	if version < 1 {
+		return 0, fmt.Errorf("version must be a positive integer")
+	}
+	if version > currentConfigVersion {
+		return 0, fmt.Errorf("configuration version %d is newer than version %d supported by this release. Upgrade godaddy-ddns", version, currentConfigVersion)
+	}
+	return version, nil
+}
+
+// migrateConfigMap upgrades parsed configuration to current version and returns its original version.
+func migrateConfigMap(config map[string]interface{}) (int, error) {
+	version, err := configVersion(config)
+	if err != nil {
+		return 0, err
+	}
+
+	for v := version; v < currentConfigVersion; v++ {
+		err = configMigrations[v-1](config)
+		if err != nil {
+			return version, fmt.Errorf("migrating from version %d %w", v, err)
+		}
+	}
+	config["version"] = currentConfigVersion
+	return version, nil
+}
+
+// storedConfigVersion returns version of configuration file. Missing file is current version.
+func storedConfigVersion() (int, error) {
+	content, err := readConfigFile()
+	if err != nil {
+		return 0, err
+	}
+	generic, err := parseConfigMap(content)
+	if err != nil || generic == nil {
+		return currentConfigVersion, err
+	}
+	return configVersion(generic)
+}
+
+// readProperties reads records from godaddy-ddns.properties of vZ releases. Each record is a
+// block of key=value lines starting with domain. Lines starting with # are comments.
+func readProperties(file string) ([]DNSRecord, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []DNSRecord
+	var starts []int
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: expected key=value", file, lineNumber)
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.Trim(strings.TrimSpace(line[i+1:]), `"'`)
+
+		if key == "domain" && (len(records) == 0 || records[len(records)-1].Domain != "") {
+			records = append(records, DNSRecord{})
+			starts = append(starts, lineNumber)
+		}
+		if len(records) == 0 {
+			return nil, fmt.Errorf("%s:%d: record must start with domain", file, lineNumber)
+		}
+		record := &records[len(records)-1]
+
+		switch key {
+		case "domain":
+			record.Domain = value
+		case "name":
+			record.Name = value
+		case "ttl":
+			record.TTL, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: ttl %q is not a number of seconds", file, lineNumber, value)
+			}
+		case "key":
+			record.Key = value
+		case "secret":
+			record.Secret = value
+		default:
+			GoDaddyDDNSLogger(WarningLog, "", "", fmt.Sprintf("%s:%d: Ignoring unknown property %s", file, lineNumber, key))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if records[i].TTL == 0 {
+			records[i].TTL = 600
+		}
+		if records[i].Name == "" || records[i].Key == "" || records[i].Secret == "" {
+			return nil, fmt.Errorf("%s:%d: record needs domain, name, key and secret", file, starts[i])
+		}
+		if records[i].TTL < 600 {
+			return nil, fmt.Errorf("%s:%d: ttl cannot be less than 600 seconds", file, starts[i])
+		}
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: no record found", file)
+	}
+	return records, nil
+}
+
+// migrateConfigFile upgrades configuration file to current version and adds records of
+// properties file if given. Previous file is kept as <file>.v<version>.bak.
+func migrateConfigFile(settings Settings, properties string, dryRun bool) error {
+	unlock, err := lockConfigFile(settings.ConfigFile)
+	if err != nil {
+		return &ConfigError{Op: "Error locking", Err: err}
+	}
+	defer unlock()
+
+	path, err := configFilePath()
+	if err != nil {
+		return &ConfigError{Op: "Error reading", Err: err}
+	}
+	original, err := readConfigFile()
+	if err != nil {
+		return &ConfigError{Op: "Error reading", Err: err}
+	}
+
+	version := currentConfigVersion
+	generic, err := parseConfigMap(original)
+	if err == nil && generic != nil {
+		version, err = configVersion(generic)
+	}
+	if err != nil {
+		return &ConfigError{Op: "Error migrating", Err: err}
+	}
+
+	config, err := decodeConfiguration(original)
+	if err != nil {
+		return &ConfigError{Op: "Error migrating", Err: err}
+	}
+
+	added := 0
+	if properties != "" {
+		records, err := readProperties(properties)
+		if err != nil {
+			return &ConfigError{Op: "Error migrating", Err: err}
+		}
+		for _, record := range records {
+			exists := false
+			for _, configured := range config.Config {
+				exists = exists || (configured.Domain == record.Domain && configured.Name == record.Name)
+			}
+			if exists {
+				GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "Record already configured. Skipping record of "+properties)
+				continue
+			}
+			config.Config = append(config.Config, record)
+			added++
+		}
+	} else if version == currentConfigVersion {
+		if original == nil {
+			return &ConfigError{Op: "Error migrating", Err: errors.New("configuration file " + path + " does not exist")}
+		}
+		GoDaddyDDNSLogger(InformationLog, "", "", fmt.Sprintf("Configuration is already version %d", currentConfigVersion))
+		return nil
+	}
+
+	if added != 0 && len(config.Config) > settings.Daemon.MaxRecords {
+		return fmt.Errorf("%w. maximum %v records allowed per server, %d configured after adding records of %s", ErrRecordLimit, settings.Daemon.MaxRecords, len(config.Config), properties)
+	}
+
+	if dryRun {
+		// Keys and secrets are masked, as they may not be encrypted yet
+		copySecrets(&config)
+		transformSecrets(&config, func(value string) (string, error) {
+			if value == "" {
+				return value, nil
+			}
+			return maskKey(value), nil
+		})
+		config.Version = currentConfigVersion
+		content, err := marshalConfiguration(config)
+		if err != nil {
+			return &ConfigError{Op: "Error marshalling", Err: err}
+		}
+		fmt.Println(string(content))
+		return nil
+	}
+
+	content, err := encodeConfiguration(config)
+	if err != nil {
+		return err
+	}
+
+	// Plain text secrets are not kept in backups once secrets are encrypted
+	if original != nil && !plaintextBackup(original, content) {
+		backup := fmt.Sprintf("%s.v%d.bak", path, version)
+		err = writeFileAtomic(backup, original, config_file_perm)
+		if err != nil {
+			return &ConfigError{Op: "Error backing up", Err: err}
+		}
+		GoDaddyDDNSLogger(InformationLog, "", "", "Previous configuration saved as "+backup)
+	}
+
+	err = writeConfigFile(content, settings.ConfigFile)
+	if err != nil {
+		return &ConfigError{Op: "Error writing", Err: err}
+	}
+
+	if version < currentConfigVersion {
+		GoDaddyDDNSLogger(InformationLog, "", "", fmt.Sprintf("Configuration migrated from version %d to %d", version, currentConfigVersion))
+	}
+	if properties != "" {
+		GoDaddyDDNSLogger(InformationLog, "", "", fmt.Sprintf("%d record(s) added from %s", added, properties))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadProperties(t *testing.T) {
+	records, err := readProperties(filepath.Join("testdata", "godaddy-ddns.properties"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DNSRecord{
+		{Domain: "example.com", Name: "home", TTL: 1200, Key: "kEyGeneratedFr0mG0DaddY", Secret: "s3cRe7GeneratedFr0mG0DaddY"},
+		{Domain: "example.org", Name: "office", TTL: 600, Key: "kEy2", Secret: "s3cRe72"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if records[i].Domain != want[i].Domain || records[i].Name != want[i].Name || records[i].TTL != want[i].TTL || records[i].Key != want[i].Key || records[i].Secret != want[i].Secret {
+			t.Errorf("record %d is %+v, want %+v", i, records[i], want[i])
+		}
+	}
+}
+
+func TestReadPropertiesErrors(t *testing.T) {
+	tests := map[string]string{
+		"name=home\n":                            "must start with domain",
+		"domain=example.com\nname=home\nkey=k\n": "needs domain, name, key and secret",
+		"domain=example.com\nname=home\nttl=60\nkey=k\nsecret=s\n": "less than 600",
+		"domain=example.com\nname home\n":                          "expected key=value",
+	}
+
+	for content, want := range tests {
+		file := filepath.Join(t.TempDir(), "godaddy-ddns.properties")
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := readProperties(file)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want error containing %q", content, err, want)
+		}
+	}
+}
+
+func TestMigrateRecordLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("GODADDYDDNS_CONFIG", path)
+
+	settings := defaultSettings()
+	settings.Daemon.MaxRecords = 1
+	err := migrateConfigFile(settings, filepath.Join("testdata", "godaddy-ddns.properties"), false)
+	if !errors.Is(err, ErrRecordLimit) {
+		t.Errorf("got %v, want ErrRecordLimit", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("configuration written over record limit")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// config_path is set by --config flag.
+var config_path string
+
+// configFilePath returns the configuration file from --config flag, GODADDYDDNS_CONFIG,
+// $XDG_CONFIG_HOME/godaddy-ddns/config.json or ~/.config/godaddy-ddns/config.json, in that order.
+// Home directory is looked up only when needed, and unknown home is an error.
+func configFilePath() (string, error) {
+	if path := explicitConfigFilePath(); path != "" {
+		return path, nil
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "godaddy-ddns", config_file), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return "", ErrNoConfigDir
+	}
+	return filepath.Join(home, ".config", "godaddy-ddns", config_file), nil
+}
+
+// explicitConfigFilePath returns the configuration file chosen by --config flag or
+// GODADDYDDNS_CONFIG, or empty string when the default is used.
+func explicitConfigFilePath() string {
+	if config_path != "" {
+		return config_path
+	}
+	return os.Getenv("GODADDYDDNS_CONFIG")
+}
+
+// stateDir returns the directory of logs, lock and state files. It is GODADDYDDNS_STATE_DIR,
+// STATE_DIRECTORY of systemd, $XDG_STATE_HOME/godaddy-ddns or else the directory of
+// configuration file, in that order. Except GODADDYDDNS_STATE_DIR, these are shared by
+// all configuration files, so instances with their own configuration file get their own
+// directory in them.
+func stateDir() (string, error) {
+	if dir := os.Getenv("GODADDYDDNS_STATE_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("STATE_DIRECTORY"); dir != "" {
+		// systemd sets multiple directories separated by colon
+		return instanceStateDir(strings.Split(dir, ":")[0])
+	}
+	if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+		return instanceStateDir(filepath.Join(xdg, "godaddy-ddns"))
+	}
+	path, err := configFilePath()
+	if err != nil {
+		return "", err
+	}
+	return instanceStateDir(filepath.Dir(path))
+}
+
+// instanceStateDir returns dir, or for configuration file given by --config or
+// GODADDYDDNS_CONFIG, its subdirectory named after the file, e.g. home-1a2b3c4d for
+// /etc/godaddy-ddns/home.json. Hash of absolute path tells apart files with same name.
+func instanceStateDir(dir string) (string, error) {
+	path := explicitConfigFilePath()
+	if path == "" {
+		return dir, nil
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(path))
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return filepath.Join(dir, name+"-"+hex.EncodeToString(sum[:4])), nil
+}
+
+// logFilePath returns log file from logging settings or else log/godaddy-ddns.log in state directory.
+func logFilePath() (string, error) {
+	if logging.File != "" && logging.File != "off" {
+		return logging.File, nil
+	}
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "log", "godaddy-ddns.log"), nil
+}
+
+func lockFilePath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "daemon.lock"), nil
+}
+
+func stateFilePath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, state_file), nil
+}
+
+// readConfigFile returns content of configuration file. Missing file is an empty configuration.
+func readConfigFile() ([]byte, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// parseGlobalFlags consumes --config flag given before the command and returns other arguments.
+func parseGlobalFlags(args []string) []string {
+	for len(args) > 0 {
+		arg := args[0]
+		switch {
+		case arg == "--config" || arg == "-config":
+			if len(args) < 2 {
+				return args
+			}
+			config_path = args[1]
+			args = args[2:]
+		case strings.HasPrefix(arg, "--config=") || strings.HasPrefix(arg, "-config="):
+			config_path = arg[strings.Index(arg, "=")+1:]
+			args = args[1:]
+		default:
+			return args
+		}
+	}
+	return args
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigFilePathWithoutHome(t *testing.T) {
+	t.Setenv("GODADDYDDNS_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "")
+
+	_, err := configFilePath()
+	if !errors.Is(err, ErrNoConfigDir) {
+		t.Errorf("got %v, want ErrNoConfigDir", err)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	path, err := configFilePath()
+	if err != nil || path != filepath.Join("/xdg", "godaddy-ddns", "config.json") {
+		t.Errorf("got %q, %v", path, err)
+	}
+}
+
+func TestStateDirPerConfigFile(t *testing.T) {
+	t.Setenv("GODADDYDDNS_STATE_DIR", "")
+	t.Setenv("STATE_DIRECTORY", "")
+	t.Setenv("XDG_STATE_HOME", "/state")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+
+	t.Setenv("GODADDYDDNS_CONFIG", "")
+	dir, err := stateDir()
+	if err != nil || dir != filepath.Join("/state", "godaddy-ddns") {
+		t.Errorf("default configuration: got %q, %v", dir, err)
+	}
+
+	dirs := make(map[string]bool)
+	for _, config := range []string{"/etc/a/home.json", "/etc/b/home.json", "/etc/a/office.json"} {
+		t.Setenv("GODADDYDDNS_CONFIG", config)
+		dir, err := stateDir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Dir(dir) != filepath.Join("/state", "godaddy-ddns") {
+			t.Errorf("%s: state directory %s is not in XDG_STATE_HOME", config, dir)
+		}
+		if dirs[dir] {
+			t.Errorf("%s: state directory %s is shared", config, dir)
+		}
+		dirs[dir] = true
+	}
+
+	// Configuration files in the same directory do not share state directory
+	t.Setenv("XDG_STATE_HOME", "")
+	dirs = make(map[string]bool)
+	for _, config := range []string{"/etc/godaddy-ddns/home.json", "/etc/godaddy-ddns/work.json"} {
+		t.Setenv("GODADDYDDNS_CONFIG", config)
+		dir, err := stateDir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Dir(dir) != "/etc/godaddy-ddns" || dirs[dir] {
+			t.Errorf("%s: state directory %s is shared or not next to configuration file", config, dir)
+		}
+		dirs[dir] = true
+	}
+
+	t.Setenv("GODADDYDDNS_STATE_DIR", "/run/gd")
+	dir, err = stateDir()
+	if err != nil || dir != "/run/gd" {
+		t.Errorf("GODADDYDDNS_STATE_DIR: got %q, %v", dir, err)
+	}
+}
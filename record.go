@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// stringList is a flag which can be passed multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+type recordOptions struct {
+	domain     string
+	recordType string
+	name       string
+	data       stringList
+	ttl        int
+	priority   int
+	service    string
+	protocol   string
+	port       int
+	weight     int
+	key        string
+	secret     string
+}
+
+func recordFlagSet(action string, withValues bool) (*flag.FlagSet, *recordOptions) {
+	opts := &recordOptions{}
+	cmd := flag.NewFlagSet("record "+action, flag.ExitOnError)
+
+	cmd.StringVar(&opts.domain, "domain", "", "Domain name e.g. example.com")
+	cmd.StringVar(&opts.recordType, "type", "", "Record type. One of "+strings.Join(godaddy.RecordTypes, ", "))
+	cmd.StringVar(&opts.name, "name", "", "Subdomain or hostname e.g. www. Use @ for domain itself")
+	cmd.StringVar(&opts.key, "key", "", "Key value generated from godaddy developer console. Defaults to key of a configured record of the domain")
+	cmd.StringVar(&opts.secret, "secret", "", "Secret value generated from godaddy developer console. Defaults to secret of a configured record of the domain")
+
+	if withValues {
+		cmd.Var(&opts.data, "data", "Record value. Repeat to set multiple values")
+		cmd.IntVar(&opts.ttl, "ttl", 600, "Time-to-live in seconds. Minimum 600 seconds.")
+		cmd.IntVar(&opts.priority, "priority", 0, "Priority of MX and SRV records")
+		cmd.StringVar(&opts.service, "service", "", "Service of SRV record e.g. _sip")
+		cmd.StringVar(&opts.protocol, "protocol", "", "Protocol of SRV record e.g. _tcp")
+		cmd.IntVar(&opts.port, "port", 0, "Port of SRV record")
+		cmd.IntVar(&opts.weight, "weight", 0, "Weight of SRV record")
+	}
+
+	return cmd, opts
+}
+
+func (opts *recordOptions) records() []godaddy.GodaddyRecordBody {
+	var records []godaddy.GodaddyRecordBody
+	for _, data := range opts.data {
+		record := godaddy.GodaddyRecordBody{
+			Data: data,
+			Name: opts.name,
+			TTL:  opts.ttl,
+			Type: opts.recordType,
+		}
+		switch opts.recordType {
+		case godaddy.TypeMX:
+			record.Priority = godaddy.Int(opts.priority)
+		case godaddy.TypeSRV:
+			record.Priority = godaddy.Int(opts.priority)
+			record.Service = opts.service
+			record.Protocol = opts.protocol
+			record.Port = godaddy.Int(opts.port)
+			record.Weight = godaddy.Int(opts.weight)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func recordUsage() {
+	fmt.Printf("\nUsage:\n")
+	fmt.Printf("\trecord get|add|replace|delete [options]\n")
+	fmt.Printf("\nget\n")
+	fmt.Printf("\tList records of the domain. Filter by --type and --name\n")
+	fmt.Printf("\nadd\n")
+	fmt.Printf("\tAdd records without touching existing records of same type and name\n")
+	fmt.Printf("\nreplace\n")
+	fmt.Printf("\tReplace all records of the type and name\n")
+	fmt.Printf("\ndelete\n")
+	fmt.Printf("\tDelete all records of the type and name\n")
+	fmt.Printf("\n\nExamples\n")
+	fmt.Printf("\tgodaddyddns record get --domain='example.com' --type=TXT\n")
+	fmt.Printf("\tgodaddyddns record add --domain='example.com' --type=MX --name='@' --data='mail.example.com' --priority=10\n")
+	fmt.Printf("\tgodaddyddns record replace --domain='example.com' --type=A --name='www' --data='1.2.3.4' --data='5.6.7.8' --ttl=1200\n")
+	fmt.Printf("\tgodaddyddns record add --domain='example.com' --type=SRV --name='@' --service=_sip --protocol=_tcp --port=5060 --weight=10 --priority=5 --data='sip.example.com'\n")
+	fmt.Printf("\tgodaddyddns record delete --domain='example.com' --type=CNAME --name='blog'\n")
+}
+
+func recordCmd(args []string, settings Settings) {
+	if len(args) < 1 {
+		recordUsage()
+		os.Exit(ExitUsage)
+	}
+
+	action := args[0]
+	withValues := action == "add" || action == "replace"
+
+	if action != "get" && !withValues && action != "delete" {
+		recordUsage()
+		os.Exit(ExitUsage)
+	}
+
+	cmd, opts := recordFlagSet(action, withValues)
+	cmd.Parse(args[1:])
+	opts.recordType = strings.ToUpper(opts.recordType)
+
+	if opts.domain == "" || (action != "get" && (opts.recordType == "" || opts.name == "")) {
+		fmt.Println("ERROR domain, type and name are mandatory")
+		fmt.Printf("\nUsage of record %s:\n", action)
+		cmd.PrintDefaults()
+		os.Exit(ExitUsage)
+	}
+
+	if withValues {
+		if len(opts.data) == 0 {
+			fmt.Println("ERROR data is mandatory")
+			fmt.Printf("\nUsage of record %s:\n", action)
+			cmd.PrintDefaults()
+			os.Exit(ExitUsage)
+		}
+		for _, record := range opts.records() {
+			if err := godaddy.ValidateRecord(record); err != nil {
+				fmt.Println("ERROR", err.Error())
+				os.Exit(ExitUsage)
+			}
+		}
+	}
+
+	key, secret, err := credentialsForDomain(opts.domain, opts.key, opts.secret)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, opts.name, opts.domain, err.Error())
+		os.Exit(exitCode(err))
+	}
+
+	client := newGodaddyClient(settings.HTTP, opts.name, opts.domain, key, secret)
+	ctx := context.Background()
+
+	switch action {
+	case "get":
+		var records []godaddy.GodaddyRecordBody
+		records, err = client.GetRecords(ctx, opts.domain, opts.recordType, opts.name)
+		if err == nil {
+			printRecords(records)
+		}
+
+	case "add":
+		err = client.AddRecords(ctx, opts.domain, opts.records())
+		if err == nil {
+			GoDaddyDDNSLogger(InformationLog, opts.name, opts.domain, opts.recordType+" record added (data: "+opts.data.String()+")")
+		}
+
+	case "replace":
+		err = client.ReplaceRecords(ctx, opts.domain, opts.recordType, opts.name, opts.records())
+		if err == nil {
+			GoDaddyDDNSLogger(InformationLog, opts.name, opts.domain, opts.recordType+" records replaced (data: "+opts.data.String()+")")
+		}
+
+	case "delete":
+		err = client.DeleteRecords(ctx, opts.domain, opts.recordType, opts.name)
+		if err == nil {
+			GoDaddyDDNSLogger(InformationLog, opts.name, opts.domain, opts.recordType+" records deleted")
+		}
+	}
+
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, opts.name, opts.domain, "Failed to "+action+" "+opts.recordType+" record. "+err.Error())
+		os.Exit(exitCode(err))
+	}
+}
+
+func printRecords(records []godaddy.GodaddyRecordBody) {
+	t := table.NewWriter()
+
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Type", "Name", "Data", "TTL", "Priority", "Details"})
+
+	for i, rec := range records {
+		details := ""
+		if rec.Type == godaddy.TypeSRV {
+			details = fmt.Sprintf("%s.%s port=%d weight=%d", rec.Service, rec.Protocol, godaddy.IntValue(rec.Port), godaddy.IntValue(rec.Weight))
+		}
+		priority := ""
+		if rec.Type == godaddy.TypeMX || rec.Type == godaddy.TypeSRV {
+			priority = fmt.Sprintf("%d", godaddy.IntValue(rec.Priority))
+		}
+		t.AppendRow(table.Row{i + 1, rec.Type, rec.Name, rec.Data, rec.TTL, priority, details})
+	}
+	t.Render()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// Strategy decides how the public IP is written to A records of the name.
+// replace makes the IP the only A record. merge adds the IP next to those of other
+// hosts, e.g. for round-robin names, and removes only the IP this server wrote earlier.
+const (
+	StrategyReplace string = "replace"
+	StrategyMerge   string = "merge"
+)
+
+var state_file string = "state.json"
+
+// recordsState holds the IP last written by this server for each record, which is
+// needed to remove only our own value when the public IP changes, and the backup IP of
+// records which are failed over, so that failover survives restart of the daemon.
+type recordsState struct {
+	LastIP   map[string]string `json:"last_ip"`
+	Failover map[string]string `json:"failover,omitempty"`
+}
+
+var stateMutex sync.Mutex
+
+func loadState() (recordsState, error) {
+	state := recordsState{LastIP: make(map[string]string), Failover: make(map[string]string)}
+
+	path, err := stateFilePath()
+	if err != nil {
+		return state, err
+	}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(content) == 0) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	err = json.Unmarshal(content, &state)
+	if state.LastIP == nil {
+		state.LastIP = make(map[string]string)
+	}
+	if state.Failover == nil {
+		state.Failover = make(map[string]string)
+	}
+	return state, err
+}
+
+// lastIP returns the IP last written by this server for the record.
+func lastIP(record DNSRecord) string {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	state, err := loadState()
+	if err != nil {
+		GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "Failed to read state file. "+err.Error())
+	}
+	return state.LastIP[recordId(record)]
+}
+
+// saveLastIP records ip as the value written by this server for the record.
+func saveLastIP(record DNSRecord, ip string) {
+	saveStateValue(record, func(state recordsState) map[string]string { return state.LastIP }, ip)
+}
+
+// failoverBackup returns the backup IP the record is failed over to, if any.
+func failoverBackup(record DNSRecord) string {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	state, err := loadState()
+	if err != nil {
+		GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "Failed to read state file. "+err.Error())
+	}
+	return state.Failover[recordId(record)]
+}
+
+// saveFailoverBackup records the backup IP the record is failed over to. Empty backup
+// records that the record points to public IP.
+func saveFailoverBackup(record DNSRecord, backup string) {
+	saveStateValue(record, func(state recordsState) map[string]string { return state.Failover }, backup)
+}
+
+// saveStateValue sets value of the record in the map of state file selected by values.
+// Empty value removes the record from the map. State file which cannot be read is left as
+// it is, as writing it would lose values of other records.
+func saveStateValue(record DNSRecord, values func(recordsState) map[string]string, value string) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	state, err := loadState()
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, record.Name, record.Domain, "Failed to read state file. Not writing state. "+err.Error())
+		return
+	}
+	if values(state)[recordId(record)] == value {
+		return
+	}
+	if value == "" {
+		delete(values(state), recordId(record))
+	} else {
+		values(state)[recordId(record)] = value
+	}
+
+	var path string
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		path, err = stateFilePath()
+	}
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), config_dir_perm)
+	}
+	if err == nil {
+		err = writeFileAtomic(path, content, config_file_perm)
+	}
+	if err != nil {
+		GoDaddyDDNSLogger(WarningLog, record.Name, record.Domain, "Failed to write state file. "+err.Error())
+	}
+}
+
+// planRecord returns the A records the name should have, the IP and ttl of our current
+// value and if the records need to be updated. With merge strategy and no current value
+// of ours, desired records are current records and our IP, which is added without PUT.
+func planRecord(record DNSRecord, current []godaddy.GodaddyRecordBody, pubIp, previousIp string) ([]godaddy.GodaddyRecordBody, string, int, bool) {
+	own := godaddy.GodaddyRecordBody{Data: pubIp, TTL: record.TTL}
+
+	if record.Strategy != StrategyMerge {
+		var existingIp string
+		var existingTtl int
+		if len(current) != 0 {
+			existingIp = current[0].Data
+			existingTtl = current[0].TTL
+		}
+		changed := len(current) != 1 || existingIp != pubIp || existingTtl != record.TTL
+		return []godaddy.GodaddyRecordBody{own}, existingIp, existingTtl, changed
+	}
+
+	var existingIp string
+	var existingTtl int
+	var desired []godaddy.GodaddyRecordBody
+	for _, r := range current {
+		if r.Data == pubIp || (previousIp != "" && r.Data == previousIp) {
+			if existingIp == "" || r.Data == previousIp {
+				existingIp, existingTtl = r.Data, r.TTL
+			}
+			continue
+		}
+		desired = append(desired, godaddy.GodaddyRecordBody{Data: r.Data, TTL: r.TTL})
+	}
+	desired = append(desired, own)
+
+	return desired, existingIp, existingTtl, !sameRecords(current, desired, record.Domain)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveStateKeepsOtherRecords(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GODADDYDDNS_STATE_DIR", dir)
+
+	home := DNSRecord{Domain: "example.com", Name: "home"}
+	office := DNSRecord{Domain: "example.com", Name: "office"}
+	saveLastIP(home, "198.51.100.1")
+	saveFailoverBackup(home, "203.0.113.10")
+	saveLastIP(office, "198.51.100.2")
+	if lastIP(home) != "198.51.100.1" || failoverBackup(home) != "203.0.113.10" || lastIP(office) != "198.51.100.2" {
+		t.Fatalf("state of records not kept")
+	}
+
+	// Truncated state file is not written over
+	path := filepath.Join(dir, state_file)
+	truncated := []byte(`{"last_ip": {"example.com:home": "198.51.100.1", "exam`)
+	if err := ioutil.WriteFile(path, truncated, 0600); err != nil {
+		t.Fatal(err)
+	}
+	saveLastIP(office, "198.51.100.3")
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(truncated) {
+		t.Errorf("state file which cannot be read was written: %s", content)
+	}
+}
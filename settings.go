@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// Settings tune the daemon, logging, detection of public IP, GoDaddy API requests,
+// notifications and writes of configuration file. They are set in settings block of configuration and can be overridden
+// per record. Fields which are not set keep their defaults.
+type Settings struct {
+	Daemon        *DaemonSettings       `json:"daemon,omitempty"`
+	Logging       *LoggingSettings      `json:"logging,omitempty"`
+	IPDetection   *IPDetectionSettings  `json:"ip_detection,omitempty"`
+	HTTP          *HTTPSettings         `json:"http,omitempty"`
+	Notifications *NotificationSettings `json:"notifications,omitempty"`
+	ConfigFile    *ConfigFileSettings   `json:"config_file,omitempty"`
+}
+
+type DaemonSettings struct {
+	PollInterval       Duration `json:"poll_interval,omitempty"`
+	Workers            int      `json:"workers,omitempty"`     // Records reconciled concurrently
+	MaxBackoff         Duration `json:"max_backoff,omitempty"` // Maximum delay between polls of a failing record
+	MaxRecords         int      `json:"max_records,omitempty"`
+	Lookup             string   `json:"lookup,omitempty"` // api or dns
+	VerifyPropagation  *bool    `json:"verify_propagation,omitempty"`
+	PropagationTimeout Duration `json:"propagation_timeout,omitempty"`
+	PropagationPoll    Duration `json:"propagation_poll,omitempty"` // Delay between checks of name servers
+	DNSTimeout         Duration `json:"dns_timeout,omitempty"`      // Timeout of a query to authoritative name server
+	MetricsListen      string   `json:"metrics_listen,omitempty"`
+}
+
+type LoggingSettings struct {
+	File  string `json:"file,omitempty"`  // Defaults to log/godaddy-ddns.log in state directory. off disables log file
+	Level string `json:"level,omitempty"` // info, warn or error
+}
+
+// IPDetectionSettings lists services returning public IP of the server, tried in order.
+// Response can be json with ip field or the IP as plain text.
+type IPDetectionSettings struct {
+	Sources []string `json:"sources,omitempty"`
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+type HTTPSettings struct {
+	Timeout        Duration `json:"timeout,omitempty"`
+	MaxRetries     *int     `json:"max_retries,omitempty"`
+	RetryBaseDelay Duration `json:"retry_base_delay,omitempty"`
+	RetryMaxDelay  Duration `json:"retry_max_delay,omitempty"`
+	RateLimit      int      `json:"rate_limit,omitempty"` // Requests per minute. GoDaddy allows 60 requests per minute.
+	APIURL         string   `json:"api_url,omitempty"`
+	APIVersion     string   `json:"api_version,omitempty"`
+}
+
+// NotificationSettings selects events which run notify hook. All events run it if not set.
+type NotificationSettings struct {
+	Events []string `json:"events,omitempty"`
+}
+
+// ConfigFileSettings tune commands changing configuration file.
+type ConfigFileSettings struct {
+	Backups     *int     `json:"backups,omitempty"`      // Previous versions kept as <file>.N.bak. 0 keeps none
+	LockTimeout Duration `json:"lock_timeout,omitempty"` // Maximum wait for another command changing the file
+}
+
+// Events passed to notify hook
+var notifyEvents = []string{"record-paused", "failover", "failback"}
+
+// Settings which apply to the whole process and cannot be overridden per record
+var globalOnlySettings = []string{
+	"daemon.poll_interval",
+	"daemon.workers",
+	"daemon.max_backoff",
+	"daemon.max_records",
+	"daemon.metrics_listen",
+	"logging",
+	"http.rate_limit",
+	"config_file",
+}
+
+// Duration is written as text like 90s or 5m in configuration.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be text like 90s or 5m")
+	}
+	value, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(value)
+	return nil
+}
+
+func defaultSettings() Settings {
+	verifyPropagation := false
+	maxRetries := 4
+	backups := 5
+
+	return Settings{
+		Daemon: &DaemonSettings{
+			PollInterval:       Duration(1 * time.Minute),
+			Workers:            3,
+			MaxBackoff:         Duration(1 * time.Hour),
+			MaxRecords:         5,
+			Lookup:             "api",
+			VerifyPropagation:  &verifyPropagation,
+			PropagationTimeout: Duration(5 * time.Minute),
+			PropagationPoll:    Duration(5 * time.Second),
+			DNSTimeout:         Duration(5 * time.Second),
+		},
+		Logging: &LoggingSettings{
+			Level: "info",
+		},
+		IPDetection: &IPDetectionSettings{
+			Sources: []string{"https://api.ipify.org/?format=json", "https://ipinfo.io/json"},
+			Timeout: Duration(30 * time.Second),
+		},
+		HTTP: &HTTPSettings{
+			Timeout:        Duration(30 * time.Second),
+			MaxRetries:     &maxRetries,
+			RetryBaseDelay: Duration(1 * time.Second),
+			RetryMaxDelay:  Duration(1 * time.Minute),
+			RateLimit:      50,
+			APIURL:         "https://api.godaddy.com",
+			APIVersion:     "v1",
+		},
+		Notifications: &NotificationSettings{},
+		ConfigFile: &ConfigFileSettings{
+			Backups:     &backups,
+			LockTimeout: Duration(10 * time.Second),
+		},
+	}
+}
+
+// overlay returns a copy of settings with fields set in layers applied in order.
+func (s Settings) overlay(layers ...*Settings) Settings {
+	settings := reflect.ValueOf(&s).Elem()
+
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		sections := reflect.ValueOf(layer).Elem()
+		for i := 0; i < sections.NumField(); i++ {
+			section := sections.Field(i)
+			if section.IsNil() {
+				continue
+			}
+			// Section is copied so that settings passed in are not modified
+			merged := reflect.New(section.Elem().Type())
+			if !settings.Field(i).IsNil() {
+				merged.Elem().Set(settings.Field(i).Elem())
+			}
+			for j := 0; j < section.Elem().NumField(); j++ {
+				if field := section.Elem().Field(j); !field.IsZero() {
+					merged.Elem().Field(j).Set(field)
+				}
+			}
+			settings.Field(i).Set(merged)
+		}
+	}
+
+	return s
+}
+
+// loadSettings returns settings block of configuration. Configuration which cannot be read
+// gives no settings; commands reading it report the error.
+func loadSettings() *Settings {
+	config, err := loadConfiguration()
+	if err != nil {
+		return nil
+	}
+	return config.Settings
+}
+
+func (n *NotificationSettings) enabled(event string) bool {
+	if len(n.Events) == 0 {
+		return true
+	}
+	for _, e := range n.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	rateLimiterOnce sync.Once
+	apiRateLimiter  godaddy.RateLimiter
+)
+
+// sharedRateLimiter returns the rate limiter shared by all GoDaddy API requests of the process.
+// It is created with limit of the first client.
+func sharedRateLimiter(limit int) godaddy.RateLimiter {
+	rateLimiterOnce.Do(func() {
+		apiRateLimiter = godaddy.NewRateLimiter(limit, time.Minute)
+	})
+	return apiRateLimiter
+}
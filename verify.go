@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// GoDaddy name servers are nsXX.domaincontrol.com. Records of domains using other name
+// servers can be updated in GoDaddy but are never served.
+const godaddyNameserverSuffix string = ".domaincontrol.com"
+
+// checkDomain confirms that credentials of the client can read the domain, the domain is
+// active and it uses GoDaddy name servers.
+func checkDomain(client *godaddy.Client, domain string) error {
+	details, err := client.GetDomain(context.Background(), domain)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(details.Status, "ACTIVE") {
+		return fmt.Errorf("%w: status is %s", ErrDomainNotActive, details.Status)
+	}
+
+	var external []string
+	for _, ns := range details.NameServers {
+		if !strings.HasSuffix(strings.ToLower(strings.TrimSuffix(ns, ".")), godaddyNameserverSuffix) {
+			external = append(external, ns)
+		}
+	}
+	if len(external) != 0 {
+		return fmt.Errorf("%w: %s", ErrExternalNameservers, strings.Join(external, ", "))
+	}
+
+	return nil
+}
+
+// verifyHint returns what to check for err of checkDomain.
+func verifyHint(err error) string {
+	var (
+		authErr     *godaddy.AuthError
+		notFoundErr *godaddy.NotFoundError
+	)
+
+	switch {
+	case errors.As(err, &authErr):
+		return "Authentication failed. Check key and secret."
+	case errors.As(err, &notFoundErr):
+		return "Domain not found in GoDaddy account of the key."
+	case errors.Is(err, ErrDomainNotActive):
+		return "Domain must be active to update its records. Check the domain in GoDaddy console."
+	case errors.Is(err, ErrExternalNameservers):
+		return "Updated records will not be served until GoDaddy name servers are used."
+	}
+	return ""
+}
+
+func verifyCmd(args []string, settings Settings) {
+	cmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	domain := cmd.String("domain", "", "Verify only records of the domain")
+	name := cmd.String("name", "", "Verify only records with the name")
+	cmd.Parse(args)
+
+	err := verifyRecords(settings, *domain, *name)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, *name, *domain, "Verification failed. "+err.Error())
+		os.Exit(exitCode(err))
+	}
+}
+
+// verifyRecords checks credentials and domain of configured records and prints the result
+// of each. It returns the error of first failed record.
+func verifyRecords(settings Settings, domain, name string) error {
+	config, err := loadConfiguration()
+	if err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"#", "Name", "Domain", "Result", "Problem"})
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 5, WidthMax: 80},
+	})
+
+	// Records of same domain, key and secret share the result
+	checked := make(map[string]error)
+	var firstErr error
+	count := 0
+
+	for _, record := range config.Config {
+		if (domain != "" && record.Domain != domain) || (name != "" && record.Name != name) {
+			continue
+		}
+		count++
+
+		resolved, err := resolveCredentials(config, record)
+		if err == nil {
+			sum := sha256.Sum256([]byte(resolved.Key + ":" + resolved.Secret))
+			cacheKey := resolved.Domain + ":" + hex.EncodeToString(sum[:])
+			var ok bool
+			if err, ok = checked[cacheKey]; !ok {
+				err = checkDomain(newGodaddyClient(settings.overlay(resolved.Settings).HTTP, resolved.Name, resolved.Domain, resolved.Key, resolved.Secret), resolved.Domain)
+				checked[cacheKey] = err
+			}
+		}
+
+		if err != nil {
+			t.AppendRow(table.Row{count, record.Name, record.Domain, "FAIL", strings.TrimSpace(verifyHint(err) + " " + err.Error())})
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			t.AppendRow(table.Row{count, record.Name, record.Domain, "OK", ""})
+		}
+	}
+
+	if count == 0 {
+		if domain == "" && name == "" {
+			return ErrNoRecords
+		}
+		return ErrRecordNotFound
+	}
+
+	t.Render()
+	return firstErr
+}
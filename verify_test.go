@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+func TestVerifySameKeyOtherSecret(t *testing.T) {
+	t.Setenv("GODADDYDDNS_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "sso-key key:secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"UNABLE_TO_AUTHENTICATE","message":"Unauthorized"}`))
+			return
+		}
+		w.Write([]byte(`{"domain":"example.com","status":"ACTIVE","nameServers":["ns1.domaincontrol.com"]}`))
+	}))
+	defer server.Close()
+
+	settings := defaultSettings().overlay(&Settings{HTTP: &HTTPSettings{APIURL: server.URL}})
+	err := saveConfiguration(Configuration{Config: []DNSRecord{
+		{Domain: "example.com", Name: "home", TTL: 600, Key: "key", Secret: "secret"},
+		{Domain: "example.com", Name: "office", TTL: 600, Key: "key", Secret: "wrong"},
+	}}, settings.ConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = verifyRecords(settings, "", "")
+	var authErr *godaddy.AuthError
+	if !errors.As(err, &authErr) {
+		t.Errorf("got %v, want AuthError of record with wrong secret", err)
+	}
+	err = verifyRecords(settings, "", "home")
+	if err != nil {
+		t.Errorf("record with right secret: got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+	"gopkg.in/yaml.v3"
+)
+
+func zoneUsage() {
+	fmt.Printf("\nUsage:\n")
+	fmt.Printf("\tzone export|import|plan|apply [options]\n")
+	fmt.Printf("\nexport\n")
+	fmt.Printf("\tExport all records of the domain as BIND zone file, json or yaml\n")
+	fmt.Printf("\nimport\n")
+	fmt.Printf("\tImport records from BIND zone file. Shows the changes and applies them on confirmation\n")
+	fmt.Printf("\nplan\n")
+	fmt.Printf("\tShow changes needed to make live records same as desired zone file (yaml or json)\n")
+	fmt.Printf("\napply\n")
+	fmt.Printf("\tApply changes of desired zone file. Records not managed by the tool are left untouched unless --prune is given\n")
+	fmt.Printf("\n\nExamples\n")
+	fmt.Printf("\tgodaddyddns zone export --domain='example.com' > example.com.zone\n")
+	fmt.Printf("\tgodaddyddns zone export --domain='example.com' --format=yaml --output=example.com.yaml\n")
+	fmt.Printf("\tgodaddyddns zone import --domain='example.com' --file=example.com.zone\n")
+	fmt.Printf("\tgodaddyddns zone plan --file=example.com.yaml\n")
+	fmt.Printf("\tgodaddyddns zone apply --file=example.com.yaml --yes\n")
+}
+
+func zoneCmd(args []string, settings Settings) {
+	if len(args) < 1 {
+		zoneUsage()
+		os.Exit(ExitUsage)
+	}
+
+	switch args[0] {
+	case "export":
+		zoneExportCmd(args[1:], settings)
+	case "import":
+		zoneImportCmd(args[1:], settings)
+	case "plan", "apply":
+		zoneApplyCmd(args[0], args[1:], settings)
+	default:
+		zoneUsage()
+		os.Exit(ExitUsage)
+	}
+}
+
+func zoneExportCmd(args []string, settings Settings) {
+	cmd := flag.NewFlagSet("zone export", flag.ExitOnError)
+	domain := cmd.String("domain", "", "Domain name e.g. example.com")
+	format := cmd.String("format", "bind", "Output format. One of bind, json, yaml")
+	output := cmd.String("output", "", "Write to file instead of stdout")
+	key := cmd.String("key", "", "Key value generated from godaddy developer console. Defaults to key of a configured record of the domain")
+	secret := cmd.String("secret", "", "Secret value generated from godaddy developer console. Defaults to secret of a configured record of the domain")
+	cmd.Parse(args)
+
+	if *domain == "" {
+		fmt.Println("ERROR domain is mandatory")
+		fmt.Printf("\nUsage of zone export:\n")
+		cmd.PrintDefaults()
+		os.Exit(ExitUsage)
+	}
+	if *format != "bind" && *format != "json" && *format != "yaml" {
+		fmt.Println("ERROR format must be one of bind, json, yaml")
+		os.Exit(ExitUsage)
+	}
+
+	err := exportZone(settings, *domain, *format, *output, *key, *secret)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", *domain, "Failed to export zone. "+err.Error())
+		os.Exit(exitCode(err))
+	}
+}
+
+func exportZone(settings Settings, domain, format, output, key, secret string) error {
+	key, secret, err := credentialsForDomain(domain, key, secret)
+	if err != nil {
+		return err
+	}
+
+	client := newGodaddyClient(settings.HTTP, "", domain, key, secret)
+	records, err := client.GetRecords(context.Background(), domain, "", "")
+	if err != nil {
+		return err
+	}
+
+	zone := Zone{Domain: domain, Records: records}
+	sortRecords(zone.Records)
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(zone)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		err = encoder.Encode(zone)
+		if err == nil {
+			err = encoder.Close()
+		}
+	default:
+		err = writeBindZone(w, zone)
+	}
+
+	return err
+}
+
+func zoneImportCmd(args []string, settings Settings) {
+	cmd := flag.NewFlagSet("zone import", flag.ExitOnError)
+	domain := cmd.String("domain", "", "Domain name e.g. example.com")
+	file := cmd.String("file", "", "BIND zone file to import")
+	noDelete := cmd.Bool("no-delete", false, "Keep records which are not in zone file")
+	dryRun := cmd.Bool("dry-run", false, "Only show the changes")
+	yes := cmd.Bool("yes", false, "Apply the changes without confirmation")
+	key := cmd.String("key", "", "Key value generated from godaddy developer console. Defaults to key of a configured record of the domain")
+	secret := cmd.String("secret", "", "Secret value generated from godaddy developer console. Defaults to secret of a configured record of the domain")
+	cmd.Parse(args)
+
+	if *domain == "" || *file == "" {
+		fmt.Println("ERROR domain and file are mandatory")
+		fmt.Printf("\nUsage of zone import:\n")
+		cmd.PrintDefaults()
+		os.Exit(ExitUsage)
+	}
+
+	err := importZone(settings, *domain, *file, *key, *secret, !*noDelete, *dryRun, *yes)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", *domain, "Failed to import zone. "+err.Error())
+		os.Exit(exitCode(err))
+	}
+}
+
+func importZone(settings Settings, domain, file, key, secret string, deletes, dryRun, yes bool) error {
+	zoneFile, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer zoneFile.Close()
+
+	desired, err := parseBindZone(zoneFile, domain, file)
+	if err != nil {
+		return err
+	}
+
+	// GoDaddy rejects invalid records only while changes are applied, after some are
+	// already done. All records are checked before showing the changes.
+	var problems []string
+	for _, record := range desired {
+		if err := godaddy.ValidateRecord(record); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %s: %s", record.Type, record.Name, err))
+		}
+	}
+	if len(problems) != 0 {
+		return fmt.Errorf("%s has records which GoDaddy does not accept\n\t%s", file, strings.Join(problems, "\n\t"))
+	}
+
+	key, secret, err = credentialsForDomain(domain, key, secret)
+	if err != nil {
+		return err
+	}
+
+	client := newGodaddyClient(settings.HTTP, "", domain, key, secret)
+	current, err := client.GetRecords(context.Background(), domain, "", "")
+	if err != nil {
+		return err
+	}
+
+	changes := diffZone(domain, current, desired, deletes)
+	printZoneChanges(changes)
+
+	if len(changes) == 0 || dryRun {
+		return nil
+	}
+
+	if !yes && !confirm("Do you want to apply these changes?") {
+		fmt.Println("Import cancelled.")
+		return nil
+	}
+
+	return applyZoneChanges(client, domain, changes)
+}
+
+func zoneApplyCmd(action string, args []string, settings Settings) {
+	cmd := flag.NewFlagSet("zone "+action, flag.ExitOnError)
+	file := cmd.String("file", "", "Desired zone file in yaml or json format. Same format as zone export")
+	prune := cmd.Bool("prune", false, "Delete records which are neither in zone file nor managed by the tool")
+	key := cmd.String("key", "", "Key value generated from godaddy developer console. Defaults to key of a configured record of the domain")
+	secret := cmd.String("secret", "", "Secret value generated from godaddy developer console. Defaults to secret of a configured record of the domain")
+	var yes *bool
+	if action == "apply" {
+		yes = cmd.Bool("yes", false, "Apply the changes without confirmation")
+	}
+	cmd.Parse(args)
+
+	if *file == "" {
+		fmt.Println("ERROR file is mandatory")
+		fmt.Printf("\nUsage of zone %s:\n", action)
+		cmd.PrintDefaults()
+		os.Exit(ExitUsage)
+	}
+
+	apply := action == "apply"
+	err := applyZoneSpec(settings, *file, *key, *secret, *prune, apply, apply && *yes)
+	if err != nil {
+		GoDaddyDDNSLogger(ErrorLog, "", "", "Failed to "+action+" zone. "+err.Error())
+		os.Exit(exitCode(err))
+	}
+}
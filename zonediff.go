@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+const (
+	ZoneCreate string = "create"
+	ZoneUpdate string = "update"
+	ZoneDelete string = "delete"
+)
+
+// zoneChange is a change to all records of a type and name, which is the unit
+// GoDaddy API replaces and deletes records in.
+type zoneChange struct {
+	Action  string
+	Type    string
+	Name    string
+	Current []godaddy.GodaddyRecordBody
+	Desired []godaddy.GodaddyRecordBody
+}
+
+type rrsetKey struct {
+	Type string
+	Name string
+}
+
+// isGodaddyManaged reports if the record is managed by GoDaddy and must not be changed,
+// which are SOA record and name servers of the domain.
+func isGodaddyManaged(record godaddy.GodaddyRecordBody) bool {
+	return record.Type == godaddy.TypeSOA || (record.Type == godaddy.TypeNS && (record.Name == "@" || record.Name == ""))
+}
+
+func groupRecords(records []godaddy.GodaddyRecordBody) map[rrsetKey][]godaddy.GodaddyRecordBody {
+	groups := make(map[rrsetKey][]godaddy.GodaddyRecordBody)
+	for _, record := range records {
+		if isGodaddyManaged(record) {
+			continue
+		}
+		name := record.Name
+		if name == "" {
+			name = "@"
+		}
+		key := rrsetKey{Type: record.Type, Name: name}
+		groups[key] = append(groups[key], record)
+	}
+	return groups
+}
+
+// normalizeHost returns host in the form GoDaddy stores it, lower case without
+// trailing dot and @ for domain itself.
+func normalizeHost(host, domain string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || host == strings.ToLower(strings.TrimSuffix(domain, ".")) {
+		return "@"
+	}
+	return host
+}
+
+// normalizeName returns record name relative to domain in the form GoDaddy stores it.
+// Names ending with the domain, with or without trailing dot, are taken as fully qualified.
+func normalizeName(name, domain string) string {
+	name = normalizeHost(name, domain)
+	return strings.TrimSuffix(name, "."+strings.ToLower(strings.TrimSuffix(domain, ".")))
+}
+
+// recordSignature is used to compare records irrespective of formatting differences.
+func recordSignature(record godaddy.GodaddyRecordBody, domain string) string {
+	data := record.Data
+	switch record.Type {
+	case godaddy.TypeCNAME, godaddy.TypeNS, godaddy.TypeMX, godaddy.TypeSRV:
+		data = normalizeHost(data, domain)
+	case godaddy.TypeAAAA, godaddy.TypeCAA:
+		data = strings.ToLower(data)
+	}
+	return fmt.Sprintf("%s|%d|%d|%s|%s|%d|%d", data, record.TTL, godaddy.IntValue(record.Priority),
+		strings.ToLower(record.Service), strings.ToLower(record.Protocol), godaddy.IntValue(record.Port), godaddy.IntValue(record.Weight))
+}
+
+func sameRecords(current, desired []godaddy.GodaddyRecordBody, domain string) bool {
+	if len(current) != len(desired) {
+		return false
+	}
+
+	var a, b []string
+	for i := range current {
+		a = append(a, recordSignature(current[i], domain))
+		b = append(b, recordSignature(desired[i], domain))
+	}
+	sort.Strings(a)
+	sort.Strings(b)
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffZone returns changes needed to make current records same as desired records.
+// Records managed by GoDaddy (SOA and name servers of the domain) are ignored.
+// If deletes is false, current records not in desired records are left untouched.
+func diffZone(domain string, current, desired []godaddy.GodaddyRecordBody, deletes bool) []zoneChange {
+	currentSets := groupRecords(current)
+	desiredSets := groupRecords(desired)
+
+	var changes []zoneChange
+
+	for key, desiredSet := range desiredSets {
+		currentSet, ok := currentSets[key]
+		if !ok {
+			changes = append(changes, zoneChange{Action: ZoneCreate, Type: key.Type, Name: key.Name, Desired: desiredSet})
+		} else if !sameRecords(currentSet, desiredSet, domain) {
+			changes = append(changes, zoneChange{Action: ZoneUpdate, Type: key.Type, Name: key.Name, Current: currentSet, Desired: desiredSet})
+		}
+	}
+
+	if deletes {
+		for key, currentSet := range currentSets {
+			if _, ok := desiredSets[key]; !ok {
+				changes = append(changes, zoneChange{Action: ZoneDelete, Type: key.Type, Name: key.Name, Current: currentSet})
+			}
+		}
+	}
+
+	sortChanges(changes)
+	return changes
+}
+
+// Deletes are applied first so that a name can change from one record type to
+// another, e.g. A to CNAME, which GoDaddy rejects while the old record exists.
+var changeOrder = map[string]int{
+	ZoneDelete: 0,
+	ZoneUpdate: 1,
+	ZoneCreate: 2,
+}
+
+func sortChanges(changes []zoneChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Action != changes[j].Action {
+			return changeOrder[changes[i].Action] < changeOrder[changes[j].Action]
+		}
+		if changes[i].Type != changes[j].Type {
+			return changes[i].Type < changes[j].Type
+		}
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+func describeRecords(records []godaddy.GodaddyRecordBody) string {
+	var values []string
+	for _, record := range records {
+		values = append(values, rdata(record)+fmt.Sprintf(" (ttl %d)", record.TTL))
+	}
+	return strings.Join(values, "\n")
+}
+
+// printZoneChanges prints the changes as a table followed by a summary.
+func printZoneChanges(changes []zoneChange) {
+	if len(changes) == 0 {
+		fmt.Println("No changes. Records are up-to-date.")
+		return
+	}
+
+	t := table.NewWriter()
+
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Action", "Type", "Name", "Current", "Desired"})
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 4, WidthMax: 60},
+		{Number: 5, WidthMax: 60},
+	})
+
+	count := make(map[string]int)
+	for _, change := range changes {
+		t.AppendRow(table.Row{change.Action, change.Type, change.Name, describeRecords(change.Current), describeRecords(change.Desired)})
+		count[change.Action]++
+	}
+	t.Render()
+
+	fmt.Printf("Plan: %d to create, %d to update, %d to delete.\n", count[ZoneCreate], count[ZoneUpdate], count[ZoneDelete])
+}
+
+// applyZoneChanges applies the changes one by one. It stops at first failure.
+func applyZoneChanges(client *godaddy.Client, domain string, changes []zoneChange) error {
+	ctx := context.Background()
+
+	for _, change := range changes {
+		var err error
+		if change.Action == ZoneDelete {
+			err = client.DeleteRecords(ctx, domain, change.Type, change.Name)
+		} else {
+			err = client.ReplaceRecords(ctx, domain, change.Type, change.Name, change.Desired)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s %s record %s %w", change.Action, change.Type, change.Name, err)
+		}
+		GoDaddyDDNSLogger(InformationLog, change.Name, domain, change.Type+" record "+change.Action+"d")
+	}
+
+	return nil
+}
+
+// confirm asks the user for confirmation on terminal.
+func confirm(question string) bool {
+	fmt.Print(question + " Only 'yes' will be accepted: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+// Zone is set of records of a domain. It is the json and yaml format of zone export
+// and the desired state for zone plan and apply. Owner identifies records managed by zone apply.
+type Zone struct {
+	Domain  string                      `json:"domain" yaml:"domain"`
+	Owner   string                      `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Records []godaddy.GodaddyRecordBody `json:"records" yaml:"records"`
+}
+
+// Order of record types in zone file. SOA and NS records go first as per convention.
+var zoneTypeOrder = map[string]int{
+	godaddy.TypeSOA:   0,
+	godaddy.TypeNS:    1,
+	godaddy.TypeA:     2,
+	godaddy.TypeAAAA:  3,
+	godaddy.TypeCNAME: 4,
+	godaddy.TypeMX:    5,
+	godaddy.TypeTXT:   6,
+	godaddy.TypeSRV:   7,
+	godaddy.TypeCAA:   8,
+}
+
+// sortRecords sorts records by type, name and data so that exports are stable and diff well in git.
+func sortRecords(records []godaddy.GodaddyRecordBody) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Type != b.Type {
+			oa, oka := zoneTypeOrder[a.Type]
+			ob, okb := zoneTypeOrder[b.Type]
+			if !oka {
+				oa = len(zoneTypeOrder)
+			}
+			if !okb {
+				ob = len(zoneTypeOrder)
+			}
+			if oa != ob {
+				return oa < ob
+			}
+			return a.Type < b.Type
+		}
+		if ownerName(a) != ownerName(b) {
+			return ownerName(a) < ownerName(b)
+		}
+		return a.Data < b.Data
+	})
+}
+
+// ownerName returns owner name of the record relative to zone origin.
+// Service and protocol of SRV records are part of its owner name.
+func ownerName(record godaddy.GodaddyRecordBody) string {
+	name := record.Name
+	if name == "" {
+		name = "@"
+	}
+	if record.Type == godaddy.TypeSRV && record.Service != "" && record.Protocol != "" {
+		if name == "@" {
+			return record.Service + "." + record.Protocol
+		}
+		return record.Service + "." + record.Protocol + "." + name
+	}
+	return name
+}
+
+// defaultZoneTTL returns most common TTL among records.
+func defaultZoneTTL(records []godaddy.GodaddyRecordBody) int {
+	count := make(map[int]int)
+	ttl := 600
+	for _, record := range records {
+		count[record.TTL]++
+		if count[record.TTL] > count[ttl] || (count[record.TTL] == count[ttl] && record.TTL < ttl) {
+			ttl = record.TTL
+		}
+	}
+	return ttl
+}
+
+// absoluteHost returns host name as written in zone file. GoDaddy stores host names
+// without trailing dot and @ for domain itself.
+func absoluteHost(host string) string {
+	if host == "@" || host == "" || strings.HasSuffix(host, ".") {
+		return host
+	}
+	return host + "."
+}
+
+// quoteTXT quotes TXT data splitting it into strings of 255 characters.
+func quoteTXT(data string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	var chunks []string
+	for len(data) > 255 {
+		chunks = append(chunks, `"`+escaped.Replace(data[:255])+`"`)
+		data = data[255:]
+	}
+	chunks = append(chunks, `"`+escaped.Replace(data)+`"`)
+	return strings.Join(chunks, " ")
+}
+
+// rdata returns record data in zone file presentation format.
+func rdata(record godaddy.GodaddyRecordBody) string {
+	switch record.Type {
+	case godaddy.TypeCNAME, godaddy.TypeNS:
+		return absoluteHost(record.Data)
+	case godaddy.TypeMX:
+		return fmt.Sprintf("%d %s", godaddy.IntValue(record.Priority), absoluteHost(record.Data))
+	case godaddy.TypeSRV:
+		return fmt.Sprintf("%d %d %d %s", godaddy.IntValue(record.Priority), godaddy.IntValue(record.Weight), godaddy.IntValue(record.Port), absoluteHost(record.Data))
+	case godaddy.TypeTXT:
+		return quoteTXT(record.Data)
+	}
+	return record.Data
+}
+
+// writeBindZone writes records as BIND zone file with $ORIGIN and $TTL directives.
+func writeBindZone(w io.Writer, zone Zone) error {
+	records := make([]godaddy.GodaddyRecordBody, len(zone.Records))
+	copy(records, zone.Records)
+	sortRecords(records)
+
+	ttl := defaultZoneTTL(records)
+
+	_, err := fmt.Fprintf(w, "; Zone %s exported by godaddy-ddns %s\n", zone.Domain, version)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "$ORIGIN %s.\n", strings.TrimSuffix(zone.Domain, "."))
+	fmt.Fprintf(w, "$TTL %d\n\n", ttl)
+
+	width := 1
+	for _, record := range records {
+		if len(ownerName(record)) > width {
+			width = len(ownerName(record))
+		}
+	}
+
+	for _, record := range records {
+		_, err = fmt.Fprintf(w, "%-*s %-6d IN %-5s %s\n", width, ownerName(record), record.TTL, record.Type, rdata(record))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unescapeTXT reverts escaping of TXT strings in zone file presentation format.
+func unescapeTXT(data string) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		if data[i] != '\\' || i+1 == len(data) {
+			b.WriteByte(data[i])
+			continue
+		}
+		if i+3 < len(data) && isDigit(data[i+1]) && isDigit(data[i+2]) && isDigit(data[i+3]) {
+			b.WriteByte(byte((data[i+1]-'0')*100 + (data[i+2]-'0')*10 + (data[i+3] - '0')))
+			i += 3
+			continue
+		}
+		b.WriteByte(data[i+1])
+		i++
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// relativeName returns name relative to origin as GoDaddy stores it.
+func relativeName(fqdn, origin string) string {
+	fqdn = strings.ToLower(dns.Fqdn(fqdn))
+	origin = strings.ToLower(dns.Fqdn(origin))
+	if fqdn == origin {
+		return "@"
+	}
+	return strings.TrimSuffix(fqdn, "."+origin)
+}
+
+// parseBindZone parses BIND zone file of the domain into GoDaddy records.
+// SOA records and unsupported record types are skipped with a warning.
+func parseBindZone(r io.Reader, domain, filename string) ([]godaddy.GodaddyRecordBody, error) {
+	origin := dns.Fqdn(domain)
+	parser := dns.NewZoneParser(r, origin, filename)
+	parser.SetIncludeAllowed(false)
+
+	var records []godaddy.GodaddyRecordBody
+
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		header := rr.Header()
+		owner := strings.ToLower(header.Name)
+
+		if !dns.IsSubDomain(origin, owner) {
+			return nil, fmt.Errorf("%s: %s is not in zone %s", filename, header.Name, domain)
+		}
+
+		record := godaddy.GodaddyRecordBody{
+			Name: relativeName(owner, origin),
+			TTL:  int(header.Ttl),
+		}
+
+		switch v := rr.(type) {
+		case *dns.A:
+			record.Type = godaddy.TypeA
+			record.Data = v.A.String()
+		case *dns.AAAA:
+			record.Type = godaddy.TypeAAAA
+			record.Data = v.AAAA.String()
+		case *dns.CNAME:
+			record.Type = godaddy.TypeCNAME
+			record.Data = normalizeHost(v.Target, domain)
+		case *dns.NS:
+			record.Type = godaddy.TypeNS
+			record.Data = normalizeHost(v.Ns, domain)
+		case *dns.MX:
+			record.Type = godaddy.TypeMX
+			record.Priority = godaddy.Int(int(v.Preference))
+			record.Data = normalizeHost(v.Mx, domain)
+		case *dns.TXT:
+			record.Type = godaddy.TypeTXT
+			record.Data = unescapeTXT(strings.Join(v.Txt, ""))
+		case *dns.SRV:
+			labels := strings.SplitN(record.Name, ".", 3)
+			if len(labels) < 2 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") {
+				return nil, fmt.Errorf("%s: SRV record %s must be named _service._protocol[.name]", filename, header.Name)
+			}
+			record.Type = godaddy.TypeSRV
+			record.Service = labels[0]
+			record.Protocol = labels[1]
+			record.Name = "@"
+			if len(labels) == 3 {
+				record.Name = labels[2]
+			}
+			record.Priority = godaddy.Int(int(v.Priority))
+			record.Weight = godaddy.Int(int(v.Weight))
+			record.Port = godaddy.Int(int(v.Port))
+			record.Data = normalizeHost(v.Target, domain)
+		case *dns.CAA:
+			record.Type = godaddy.TypeCAA
+			record.Data = fmt.Sprintf("%d %s \"%s\"", v.Flag, v.Tag, v.Value)
+		case *dns.SOA:
+			continue
+		default:
+			GoDaddyDDNSLogger(WarningLog, record.Name, domain, "Skipping unsupported record type "+dns.TypeToString[header.Rrtype])
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	if err := parser.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
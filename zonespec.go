@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+	"gopkg.in/yaml.v3"
+)
+
+// Ownership of records managed by zone apply is recorded in TXT records named
+// _godaddyddns.<name> (_godaddyddns for the domain itself), one value per record type.
+// Wildcard label * is written as _wildcard, as it cannot follow other labels.
+// Records without ownership marker are never deleted unless prune is requested.
+const (
+	ownershipPrefix string = "_godaddyddns"
+	ownershipTTL    int    = 600
+	defaultOwner    string = "default"
+	wildcardLabel   string = "_wildcard"
+)
+
+// loadZoneSpec reads desired records from yaml or json file.
+func loadZoneSpec(file string) (Zone, error) {
+	var zone Zone
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return zone, err
+	}
+
+	// JSON is valid yaml. Single decoder handles both formats.
+	decoder := yaml.NewDecoder(strings.NewReader(string(content)))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&zone)
+	if err != nil {
+		return zone, fmt.Errorf("%s: %w", file, err)
+	}
+
+	if zone.Domain == "" {
+		return zone, fmt.Errorf("%s: domain is mandatory", file)
+	}
+	if zone.Owner == "" {
+		zone.Owner = defaultOwner
+	}
+	if strings.ContainsAny(zone.Owner, ", =") {
+		return zone, fmt.Errorf("%s: owner must not contain spaces, commas or '='", file)
+	}
+
+	// SOA and name servers of the domain are managed by GoDaddy. They are present in
+	// zone export and are ignored here so that an export can be used as desired zone.
+	var records []godaddy.GodaddyRecordBody
+	for _, record := range zone.Records {
+		record.Type = strings.ToUpper(record.Type)
+		record.Name = normalizeName(record.Name, zone.Domain)
+		if !isGodaddyManaged(record) {
+			records = append(records, record)
+		}
+	}
+	zone.Records = records
+
+	for i := range zone.Records {
+		record := &zone.Records[i]
+		if record.TTL == 0 {
+			record.TTL = 600
+		}
+		if isOwnershipName(record.Name) {
+			return zone, fmt.Errorf("%s: records[%d]: names starting with %s are reserved for ownership markers", file, i, ownershipPrefix)
+		}
+		if err := godaddy.ValidateRecord(*record); err != nil {
+			return zone, fmt.Errorf("%s: records[%d] (%s %s): %w", file, i, record.Type, record.Name, err)
+		}
+	}
+
+	return zone, nil
+}
+
+func ownershipName(name string) string {
+	if name == "@" || name == "" {
+		return ownershipPrefix
+	}
+	if name == "*" || strings.HasPrefix(name, "*.") {
+		name = wildcardLabel + name[1:]
+	}
+	return ownershipPrefix + "." + name
+}
+
+func isOwnershipName(name string) bool {
+	return name == ownershipPrefix || strings.HasPrefix(name, ownershipPrefix+".")
+}
+
+func ownershipValue(owner, recordType string) string {
+	return "heritage=godaddyddns,owner=" + owner + ",type=" + recordType
+}
+
+// parseOwnershipValue returns owner and record type from ownership marker value.
+func parseOwnershipValue(value string) (string, string, bool) {
+	fields := make(map[string]string)
+	for _, field := range strings.Split(value, ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) == 2 {
+			fields[parts[0]] = parts[1]
+		}
+	}
+	if fields["heritage"] != "godaddyddns" || fields["owner"] == "" || fields["type"] == "" {
+		return "", "", false
+	}
+	return fields["owner"], fields["type"], true
+}
+
+func ownerOfName(markerName string) string {
+	if markerName == ownershipPrefix {
+		return "@"
+	}
+	name := strings.TrimPrefix(markerName, ownershipPrefix+".")
+	if name == wildcardLabel || strings.HasPrefix(name, wildcardLabel+".") {
+		name = "*" + name[len(wildcardLabel):]
+	}
+	return name
+}
+
+// planZone returns the changes to make live records same as desired zone. Records owned by
+// the zone owner and removed from desired zone are deleted. Records not owned are left
+// untouched unless prune is true.
+func planZone(zone Zone, live []godaddy.GodaddyRecordBody, prune bool) []zoneChange {
+	var liveRecords, liveMarkers []godaddy.GodaddyRecordBody
+	owned := make(map[rrsetKey]bool)
+
+	for _, record := range live {
+		if record.Type == godaddy.TypeTXT && isOwnershipName(record.Name) {
+			liveMarkers = append(liveMarkers, record)
+			if owner, recordType, ok := parseOwnershipValue(record.Data); ok && owner == zone.Owner {
+				owned[rrsetKey{Type: recordType, Name: ownerOfName(record.Name)}] = true
+			}
+			continue
+		}
+		liveRecords = append(liveRecords, record)
+	}
+
+	changes := diffZone(zone.Domain, liveRecords, zone.Records, false)
+
+	desiredSets := groupRecords(zone.Records)
+	for key, liveSet := range groupRecords(liveRecords) {
+		if _, ok := desiredSets[key]; ok {
+			continue
+		}
+		if owned[key] || prune {
+			changes = append(changes, zoneChange{Action: ZoneDelete, Type: key.Type, Name: key.Name, Current: liveSet})
+		}
+	}
+
+	// Markers of other owners are kept. Our markers are rewritten for desired records.
+	var desiredMarkers []godaddy.GodaddyRecordBody
+	for _, record := range liveMarkers {
+		if owner, _, ok := parseOwnershipValue(record.Data); ok && owner == zone.Owner {
+			continue
+		}
+		desiredMarkers = append(desiredMarkers, record)
+	}
+	var keys []rrsetKey
+	for key := range desiredSets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Name+keys[i].Type < keys[j].Name+keys[j].Type
+	})
+	for _, key := range keys {
+		desiredMarkers = append(desiredMarkers, godaddy.GodaddyRecordBody{
+			Type: godaddy.TypeTXT,
+			Name: ownershipName(key.Name),
+			Data: ownershipValue(zone.Owner, key.Type),
+			TTL:  ownershipTTL,
+		})
+	}
+
+	changes = append(changes, diffZone(zone.Domain, liveMarkers, desiredMarkers, true)...)
+	sortChanges(changes)
+	return changes
+}
+
+func zoneSpecClient(settings Settings, zone Zone, key, secret string) (*godaddy.Client, []godaddy.GodaddyRecordBody, error) {
+	key, secret, err := credentialsForDomain(zone.Domain, key, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := newGodaddyClient(settings.HTTP, "", zone.Domain, key, secret)
+	live, err := client.GetRecords(context.Background(), zone.Domain, "", "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, live, nil
+}
+
+// applyZoneSpec shows the plan for the desired zone file and applies it if apply is true.
+func applyZoneSpec(settings Settings, file, key, secret string, prune, apply, yes bool) error {
+	zone, err := loadZoneSpec(file)
+	if err != nil {
+		return &ConfigError{Op: "Error reading zone", Err: err}
+	}
+
+	client, live, err := zoneSpecClient(settings, zone, key, secret)
+	if err != nil {
+		return err
+	}
+
+	changes := planZone(zone, live, prune)
+	printZoneChanges(changes)
+
+	if !apply || len(changes) == 0 {
+		return nil
+	}
+
+	if !yes && !confirm("Do you want to apply these changes?") {
+		fmt.Println("Apply cancelled.")
+		return nil
+	}
+
+	return applyZoneChanges(client, zone.Domain, changes)
+}
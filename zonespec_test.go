@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/navilg/godaddy-ddns/godaddy"
+)
+
+func TestLoadZoneSpecNames(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "zone.yaml")
+	spec := `domain: example.com
+records:
+  - {type: A, name: WWW, data: 198.51.100.1}
+  - {type: A, name: mail.example.com, data: 198.51.100.2}
+  - {type: A, name: Api.Example.COM., data: 198.51.100.3}
+  - {type: TXT, name: example.com, data: hello}
+`
+	if err := ioutil.WriteFile(file, []byte(spec), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	zone, err := loadZoneSpec(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"www", "mail", "api", "@"}
+	for i, record := range zone.Records {
+		if record.Name != want[i] {
+			t.Errorf("records[%d]: got name %q, want %q", i, record.Name, want[i])
+		}
+	}
+
+	// Live records with names as GoDaddy stores them are current
+	var live []godaddy.GodaddyRecordBody
+	for i, record := range zone.Records {
+		record.Name = want[i]
+		live = append(live, record, godaddy.GodaddyRecordBody{
+			Type: godaddy.TypeTXT,
+			Name: ownershipName(want[i]),
+			Data: ownershipValue(zone.Owner, record.Type),
+			TTL:  ownershipTTL,
+		})
+	}
+	if changes := planZone(zone, live, false); len(changes) != 0 {
+		t.Errorf("got changes %+v, want none", changes)
+	}
+}